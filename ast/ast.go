@@ -56,6 +56,45 @@ func (r *ReturnStatement) String() string {
 	return out.String()
 }
 
+type ThrowStatement struct {
+	Token token.Token
+	Value Expression
+}
+
+func (t *ThrowStatement) TokenLiteral() string {
+	return t.Token.Literal
+}
+
+func (t *ThrowStatement) statementNode() {}
+
+func (t *ThrowStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(t.TokenLiteral() + " ")
+	if t.Value != nil {
+		out.WriteString(t.Value.String())
+	}
+
+	out.WriteString(";")
+	return out.String()
+}
+
+// ExportStatement 只能出现在模块的顶层
+type ExportStatement struct {
+	Token     token.Token
+	Statement *LetStatement
+}
+
+func (es *ExportStatement) TokenLiteral() string {
+	return es.Token.Literal
+}
+
+func (es *ExportStatement) statementNode() {}
+
+func (es *ExportStatement) String() string {
+	return es.TokenLiteral() + " " + es.Statement.String()
+}
+
 type WhileStatement struct {
 	Token       token.Token
 	Condition   Expression
@@ -99,6 +138,9 @@ func (e *ExpressionStatement) String() string {
 type BlockStatement struct {
 	Token      token.Token
 	Statements []Statement
+
+	// End 是结束代码块的 }
+	End token.Token
 }
 
 func (b *BlockStatement) TokenLiteral() string {
@@ -177,6 +219,20 @@ func (il *Boolean) String() string {
 	return il.Token.Literal
 }
 
+type NullLiteral struct {
+	Token token.Token
+}
+
+func (n *NullLiteral) TokenLiteral() string {
+	return n.Token.Literal
+}
+
+func (n *NullLiteral) expressionNode() {}
+
+func (n *NullLiteral) String() string {
+	return n.Token.Literal
+}
+
 type PrefixExpression struct {
 	Token    token.Token
 	Operator string
@@ -253,6 +309,56 @@ func (ie *IfExpression) String() string {
 	return out.String()
 }
 
+// TryExpression 中 Catch 和 Finally 至少有一个，Param 可以省略
+type TryExpression struct {
+	Token   token.Token
+	Block   *BlockStatement
+	Param   *Identifier
+	Catch   *BlockStatement
+	Finally *BlockStatement
+}
+
+func (te *TryExpression) TokenLiteral() string {
+	return te.Token.Literal
+}
+
+func (te *TryExpression) expressionNode() {}
+
+func (te *TryExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try ")
+	out.WriteString(te.Block.String())
+
+	if te.Catch != nil {
+		out.WriteString("catch ")
+		if te.Param != nil {
+			out.WriteString("(" + te.Param.String() + ") ")
+		}
+		out.WriteString(te.Catch.String())
+	}
+	if te.Finally != nil {
+		out.WriteString("finally ")
+		out.WriteString(te.Finally.String())
+	}
+	return out.String()
+}
+
+type ImportExpression struct {
+	Token token.Token
+	Path  string
+}
+
+func (ie *ImportExpression) TokenLiteral() string {
+	return ie.Token.Literal
+}
+
+func (ie *ImportExpression) expressionNode() {}
+
+func (ie *ImportExpression) String() string {
+	return ie.TokenLiteral() + " \"" + ie.Path + "\""
+}
+
 type CallExpression struct {
 	Token     token.Token
 	Function  Expression
@@ -285,6 +391,9 @@ type IndexExpression struct {
 	Token token.Token
 	Left  Expression
 	Index Expression
+
+	// Optional 为 true 时表示 ?[ 形式，Left 为 null 时整条成员访问、下标和调用链的结果为 null
+	Optional bool
 }
 
 func (i *IndexExpression) TokenLiteral() string {
@@ -298,6 +407,9 @@ func (i *IndexExpression) String() string {
 
 	out.WriteString("(")
 	out.WriteString(i.Left.String())
+	if i.Optional {
+		out.WriteString("?")
+	}
 	out.WriteString("[")
 	out.WriteString(i.Index.String())
 	out.WriteString("])")
@@ -305,10 +417,41 @@ func (i *IndexExpression) String() string {
 	return out.String()
 }
 
+// MemberExpression 表示 obj.name 或 obj?.name
+type MemberExpression struct {
+	Token    token.Token
+	Object   Expression
+	Property *Identifier
+
+	// Optional 为 true 时表示 ?. 形式，Object 为 null 时整条成员访问、下标和调用链的结果为 null
+	Optional bool
+}
+
+func (m *MemberExpression) TokenLiteral() string {
+	return m.Token.Literal
+}
+
+func (m *MemberExpression) expressionNode() {}
+
+func (m *MemberExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(m.Object.String())
+	out.WriteString(m.Token.Literal)
+	out.WriteString(m.Property.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
 type FunctionLiteral struct {
 	Token      token.Token
 	Parameters []*Identifier
 	Body       *BlockStatement
+
+	// Name 是 let 语句绑定的名字，匿名函数为空
+	Name string
 }
 
 func (f *FunctionLiteral) TokenLiteral() string {
@@ -363,6 +506,9 @@ func (a *ArrayLiteral) String() string {
 type HashLiteral struct {
 	Token token.Token
 	Pairs map[Expression]Expression
+
+	// Keys 按源码顺序记录 Pairs 的键
+	Keys []Expression
 }
 
 func (h *HashLiteral) TokenLiteral() string {
@@ -375,8 +521,8 @@ func (h *HashLiteral) String() string {
 	var out bytes.Buffer
 
 	pairs := make([]string, 0)
-	for k, v := range h.Pairs {
-		pairs = append(pairs, k.String()+":"+v.String())
+	for _, k := range h.Keys {
+		pairs = append(pairs, k.String()+":"+h.Pairs[k].String())
 	}
 
 	out.WriteString("{")
@@ -1,6 +1,9 @@
 package ast
 
-import "bytes"
+import (
+	"bytes"
+	"shanyl2400/go_compiler/token"
+)
 
 type Node interface {
 	TokenLiteral() string
@@ -19,6 +22,24 @@ type Expression interface {
 
 type Program struct {
 	Statements []Statement
+
+	// File 是源码文件名，用于错误的调用栈，可以为空
+	File string
+
+	// Comments 是源码中的全部注释，按出现顺序排列
+	Comments []*Comment
+}
+
+// Comment 是一行 // 注释，Token.Literal 包括开头的 //
+type Comment struct {
+	Token token.Token
+
+	// Trailing 表示注释前同一行还有代码
+	Trailing bool
+}
+
+func (c *Comment) Text() string {
+	return c.Token.Literal
 }
 
 func (p *Program) TokenLiteral() string {
@@ -0,0 +1,154 @@
+package ast
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Fprint 以缩进的形式把语法树打印到 w，每行一个节点
+func Fprint(w io.Writer, node Node) {
+	fprint(w, node, 0)
+}
+
+func fprint(w io.Writer, node Node, depth int) {
+	label, children := treeNode(node)
+	fmt.Fprintf(w, "%s%s\n", strings.Repeat("  ", depth), label)
+	for _, child := range children {
+		fprint(w, child, depth+1)
+	}
+}
+
+// treeNode 返回节点显示的内容和子节点，nil 子节点会被忽略
+func treeNode(node Node) (string, []Node) {
+	var label string
+	var children []Node
+	add := func(nodes ...Node) {
+		for _, n := range nodes {
+			if !isNil(n) {
+				children = append(children, n)
+			}
+		}
+	}
+
+	switch node := node.(type) {
+	case *Program:
+		label = "Program"
+		for _, stmt := range node.Statements {
+			add(stmt)
+		}
+	case *LetStatement:
+		label = "LetStatement " + node.Name.Value
+		add(node.Value)
+	case *ExportStatement:
+		label = "ExportStatement"
+		add(node.Statement)
+	case *ReturnStatement:
+		label = "ReturnStatement"
+		add(node.Value)
+	case *ThrowStatement:
+		label = "ThrowStatement"
+		add(node.Value)
+	case *WhileStatement:
+		label = "WhileStatement"
+		add(node.Condition, node.Consequence)
+	case *ExpressionStatement:
+		label = "ExpressionStatement"
+		add(node.Expression)
+	case *BlockStatement:
+		label = "BlockStatement"
+		for _, stmt := range node.Statements {
+			add(stmt)
+		}
+	case *Identifier:
+		label = "Identifier " + node.Value
+	case *IntegerLiteral:
+		label = fmt.Sprintf("IntegerLiteral %d", node.Value)
+	case *StringLiteral:
+		label = fmt.Sprintf("StringLiteral %q", node.Value)
+	case *Boolean:
+		label = fmt.Sprintf("Boolean %t", node.Value)
+	case *NullLiteral:
+		label = "NullLiteral"
+	case *PrefixExpression:
+		label = "PrefixExpression " + node.Operator
+		add(node.Right)
+	case *InfixExpression:
+		label = "InfixExpression " + node.Operator
+		add(node.Left, node.Right)
+	case *IfExpression:
+		label = "IfExpression"
+		add(node.Condition, node.Consequence, node.Alternative)
+	case *TryExpression:
+		label = "TryExpression"
+		add(node.Block, node.Param, node.Catch, node.Finally)
+	case *ImportExpression:
+		label = fmt.Sprintf("ImportExpression %q", node.Path)
+	case *CallExpression:
+		label = "CallExpression"
+		add(node.Function)
+		for _, arg := range node.Arguments {
+			add(arg)
+		}
+	case *IndexExpression:
+		label = "IndexExpression"
+		if node.Optional {
+			label += " ?["
+		}
+		add(node.Left, node.Index)
+	case *MemberExpression:
+		label = "MemberExpression"
+		if node.Optional {
+			label += " ?."
+		}
+		add(node.Object, node.Property)
+	case *FunctionLiteral:
+		label = "FunctionLiteral"
+		if node.Name != "" {
+			label += " " + node.Name
+		}
+		for _, param := range node.Parameters {
+			add(param)
+		}
+		add(node.Body)
+	case *ArrayLiteral:
+		label = "ArrayLiteral"
+		for _, elem := range node.Elements {
+			add(elem)
+		}
+	case *HashLiteral:
+		label = "HashLiteral"
+		for _, key := range node.Keys {
+			add(&hashPair{key: key, value: node.Pairs[key]})
+		}
+	case *hashPair:
+		label = "HashPair"
+		add(node.key, node.value)
+	default:
+		label = fmt.Sprintf("%T", node)
+	}
+	return label, children
+}
+
+// hashPair 只用于显示 HashLiteral 的一个键值对
+type hashPair struct {
+	key, value Expression
+}
+
+func (p *hashPair) TokenLiteral() string { return "" }
+func (p *hashPair) String() string       { return p.key.String() + ":" + p.value.String() }
+
+// isNil 判断接口中是否是 nil 指针，例如没有 else 分支的 IfExpression.Alternative
+func isNil(node Node) bool {
+	switch node := node.(type) {
+	case nil:
+		return true
+	case *BlockStatement:
+		return node == nil
+	case *Identifier:
+		return node == nil
+	case *LetStatement:
+		return node == nil
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package ast
+
+import (
+	"fmt"
+	"shanyl2400/go_compiler/token"
+)
+
+// TokenOf 返回节点的 Token 字段，用于取得节点的位置。
+// 中缀、调用、下标和成员表达式的 Token 是运算符，不是最左边的 token。
+// Program 没有自己的 token，返回第一条语句的 token，没有语句时返回零值
+func TokenOf(node Node) token.Token {
+	switch n := node.(type) {
+	case *Program:
+		if len(n.Statements) == 0 {
+			return token.Token{}
+		}
+		return TokenOf(n.Statements[0])
+	case *LetStatement:
+		return n.Token
+	case *ExportStatement:
+		return n.Token
+	case *ReturnStatement:
+		return n.Token
+	case *ThrowStatement:
+		return n.Token
+	case *WhileStatement:
+		return n.Token
+	case *ExpressionStatement:
+		return n.Token
+	case *BlockStatement:
+		return n.Token
+	case *Identifier:
+		return n.Token
+	case *IntegerLiteral:
+		return n.Token
+	case *StringLiteral:
+		return n.Token
+	case *Boolean:
+		return n.Token
+	case *NullLiteral:
+		return n.Token
+	case *PrefixExpression:
+		return n.Token
+	case *InfixExpression:
+		return n.Token
+	case *IfExpression:
+		return n.Token
+	case *TryExpression:
+		return n.Token
+	case *ImportExpression:
+		return n.Token
+	case *CallExpression:
+		return n.Token
+	case *IndexExpression:
+		return n.Token
+	case *MemberExpression:
+		return n.Token
+	case *FunctionLiteral:
+		return n.Token
+	case *ArrayLiteral:
+		return n.Token
+	case *HashLiteral:
+		return n.Token
+	}
+	panic(fmt.Sprintf("ast.TokenOf: unexpected node type %T", node))
+}
@@ -0,0 +1,216 @@
+package ast
+
+import "fmt"
+
+// Visitor 的 Visit 在 Walk 访问每个节点时调用，返回的 Visitor 用于访问该节点的子节点，
+// 返回 nil 时不再访问子节点。子节点访问完后会以 nil 再调用一次 w.Visit
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk 先序遍历语法树，子节点按源码顺序访问，可选的子节点为 nil 时跳过
+func Walk(node Node, v Visitor) {
+	if isNil(node) {
+		return
+	}
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		walkStatements(n.Statements, v)
+	case *BlockStatement:
+		walkStatements(n.Statements, v)
+	case *LetStatement:
+		Walk(n.Name, v)
+		Walk(n.Value, v)
+	case *ExportStatement:
+		Walk(n.Statement, v)
+	case *ReturnStatement:
+		Walk(n.Value, v)
+	case *ThrowStatement:
+		Walk(n.Value, v)
+	case *ExpressionStatement:
+		Walk(n.Expression, v)
+	case *WhileStatement:
+		Walk(n.Condition, v)
+		Walk(n.Consequence, v)
+	case *Identifier, *IntegerLiteral, *StringLiteral, *Boolean, *NullLiteral, *ImportExpression:
+		// 没有子节点
+	case *PrefixExpression:
+		Walk(n.Right, v)
+	case *InfixExpression:
+		Walk(n.Left, v)
+		Walk(n.Right, v)
+	case *IfExpression:
+		Walk(n.Condition, v)
+		Walk(n.Consequence, v)
+		Walk(n.Alternative, v)
+	case *TryExpression:
+		Walk(n.Block, v)
+		Walk(n.Param, v)
+		Walk(n.Catch, v)
+		Walk(n.Finally, v)
+	case *CallExpression:
+		Walk(n.Function, v)
+		walkExpressions(n.Arguments, v)
+	case *IndexExpression:
+		Walk(n.Left, v)
+		Walk(n.Index, v)
+	case *MemberExpression:
+		Walk(n.Object, v)
+		Walk(n.Property, v)
+	case *FunctionLiteral:
+		for _, p := range n.Parameters {
+			Walk(p, v)
+		}
+		Walk(n.Body, v)
+	case *ArrayLiteral:
+		walkExpressions(n.Elements, v)
+	case *HashLiteral:
+		for _, k := range n.Keys {
+			Walk(k, v)
+			Walk(n.Pairs[k], v)
+		}
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+func walkStatements(stmts []Statement, v Visitor) {
+	for _, s := range stmts {
+		Walk(s, v)
+	}
+}
+
+func walkExpressions(exps []Expression, v Visitor) {
+	for _, e := range exps {
+		Walk(e, v)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if node != nil && f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect 先序遍历语法树，f 返回 false 时不再访问该节点的子节点
+func Inspect(node Node, f func(Node) bool) {
+	Walk(node, inspector(f))
+}
+
+// Modify 后序遍历语法树，先修改子节点，再用 modifier 的返回值替换节点本身，返回替换后的根节点。
+// 节点原地修改，HashLiteral 的 Keys 和 Pairs 同时更新。
+// modifier 返回的节点必须能放回原来的位置，例如表达式的位置只能放表达式，否则 panic
+func Modify(node Node, modifier func(Node) Node) Node {
+	if isNil(node) {
+		return node
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		modifyStatements(n.Statements, modifier)
+	case *BlockStatement:
+		modifyStatements(n.Statements, modifier)
+	case *LetStatement:
+		n.Name = modifyIdentifier(n.Name, modifier)
+		n.Value = modifyExpression(n.Value, modifier)
+	case *ExportStatement:
+		if n.Statement != nil {
+			n.Statement = Modify(n.Statement, modifier).(*LetStatement)
+		}
+	case *ReturnStatement:
+		n.Value = modifyExpression(n.Value, modifier)
+	case *ThrowStatement:
+		n.Value = modifyExpression(n.Value, modifier)
+	case *ExpressionStatement:
+		n.Expression = modifyExpression(n.Expression, modifier)
+	case *WhileStatement:
+		n.Condition = modifyExpression(n.Condition, modifier)
+		n.Consequence = modifyBlock(n.Consequence, modifier)
+	case *Identifier, *IntegerLiteral, *StringLiteral, *Boolean, *NullLiteral, *ImportExpression:
+		// 没有子节点
+	case *PrefixExpression:
+		n.Right = modifyExpression(n.Right, modifier)
+	case *InfixExpression:
+		n.Left = modifyExpression(n.Left, modifier)
+		n.Right = modifyExpression(n.Right, modifier)
+	case *IfExpression:
+		n.Condition = modifyExpression(n.Condition, modifier)
+		n.Consequence = modifyBlock(n.Consequence, modifier)
+		n.Alternative = modifyBlock(n.Alternative, modifier)
+	case *TryExpression:
+		n.Block = modifyBlock(n.Block, modifier)
+		n.Param = modifyIdentifier(n.Param, modifier)
+		n.Catch = modifyBlock(n.Catch, modifier)
+		n.Finally = modifyBlock(n.Finally, modifier)
+	case *CallExpression:
+		n.Function = modifyExpression(n.Function, modifier)
+		modifyExpressions(n.Arguments, modifier)
+	case *IndexExpression:
+		n.Left = modifyExpression(n.Left, modifier)
+		n.Index = modifyExpression(n.Index, modifier)
+	case *MemberExpression:
+		n.Object = modifyExpression(n.Object, modifier)
+		n.Property = modifyIdentifier(n.Property, modifier)
+	case *FunctionLiteral:
+		for i, p := range n.Parameters {
+			n.Parameters[i] = modifyIdentifier(p, modifier)
+		}
+		n.Body = modifyBlock(n.Body, modifier)
+	case *ArrayLiteral:
+		modifyExpressions(n.Elements, modifier)
+	case *HashLiteral:
+		// 键可能被替换，重新建立 Pairs
+		pairs := make(map[Expression]Expression, len(n.Keys))
+		for i, k := range n.Keys {
+			n.Keys[i] = modifyExpression(k, modifier)
+			pairs[n.Keys[i]] = modifyExpression(n.Pairs[k], modifier)
+		}
+		n.Pairs = pairs
+	default:
+		panic(fmt.Sprintf("ast.Modify: unexpected node type %T", n))
+	}
+
+	return modifier(node)
+}
+
+func modifyStatements(stmts []Statement, modifier func(Node) Node) {
+	for i, s := range stmts {
+		stmts[i] = Modify(s, modifier).(Statement)
+	}
+}
+
+func modifyExpressions(exps []Expression, modifier func(Node) Node) {
+	for i, e := range exps {
+		exps[i] = modifyExpression(e, modifier)
+	}
+}
+
+func modifyExpression(e Expression, modifier func(Node) Node) Expression {
+	if isNil(e) {
+		return e
+	}
+	return Modify(e, modifier).(Expression)
+}
+
+func modifyBlock(b *BlockStatement, modifier func(Node) Node) *BlockStatement {
+	if b == nil {
+		return nil
+	}
+	return Modify(b, modifier).(*BlockStatement)
+}
+
+func modifyIdentifier(id *Identifier, modifier func(Node) Node) *Identifier {
+	if id == nil {
+		return nil
+	}
+	return Modify(id, modifier).(*Identifier)
+}
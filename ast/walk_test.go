@@ -0,0 +1,286 @@
+package ast
+
+import (
+	goast "go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	mtoken "shanyl2400/go_compiler/token"
+)
+
+// allNodes 列出所有节点类型，新增节点时需要加在这里，TestAllNodesListed 会检查
+var allNodes = []Node{
+	&Program{},
+	&LetStatement{},
+	&ReturnStatement{},
+	&ThrowStatement{},
+	&ExportStatement{},
+	&WhileStatement{},
+	&ExpressionStatement{},
+	&BlockStatement{},
+	&Identifier{},
+	&IntegerLiteral{},
+	&StringLiteral{},
+	&Boolean{},
+	&NullLiteral{},
+	&PrefixExpression{},
+	&InfixExpression{},
+	&IfExpression{},
+	&TryExpression{},
+	&ImportExpression{},
+	&CallExpression{},
+	&IndexExpression{},
+	&MemberExpression{},
+	&FunctionLiteral{},
+	&ArrayLiteral{},
+	&HashLiteral{},
+}
+
+// sourceNodeTypes 从包的源码中找出实现了 Node 的导出类型
+func sourceNodeTypes(t *testing.T) []string {
+	t.Helper()
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, ".", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, pkg := range pkgs {
+		for path, file := range pkg.Files {
+			if strings.HasSuffix(path, "_test.go") {
+				continue
+			}
+			for _, decl := range file.Decls {
+				fn, ok := decl.(*goast.FuncDecl)
+				if !ok || fn.Recv == nil || fn.Name.Name != "TokenLiteral" {
+					continue
+				}
+				recv := fn.Recv.List[0].Type
+				if star, ok := recv.(*goast.StarExpr); ok {
+					recv = star.X
+				}
+				if name := recv.(*goast.Ident).Name; goast.IsExported(name) {
+					names = append(names, name)
+				}
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestAllNodesListed(t *testing.T) {
+	var listed []string
+	for _, n := range allNodes {
+		listed = append(listed, reflect.TypeOf(n).Elem().Name())
+	}
+	sort.Strings(listed)
+
+	if !reflect.DeepEqual(listed, sourceNodeTypes(t)) {
+		t.Fatalf("allNodes is out of date.\nlisted=%v\nsource=%v", listed, sourceNodeTypes(t))
+	}
+}
+
+var (
+	nodeType       = reflect.TypeOf((*Node)(nil)).Elem()
+	statementType  = reflect.TypeOf((*Statement)(nil)).Elem()
+	expressionType = reflect.TypeOf((*Expression)(nil)).Elem()
+)
+
+// newChild 创建一个能放在类型为 typ 的字段中的空节点
+func newChild(typ reflect.Type) reflect.Value {
+	switch typ {
+	case statementType:
+		return reflect.ValueOf(&ExpressionStatement{})
+	case expressionType, nodeType:
+		return reflect.ValueOf(&Identifier{})
+	}
+	return reflect.New(typ.Elem())
+}
+
+func isNodeField(typ reflect.Type) bool {
+	return typ.Implements(nodeType)
+}
+
+// fill 用反射给节点的每个子节点字段填上新的空节点，返回这些子节点。
+// map 字段的键取自同一结构体的 Keys 字段
+func fill(node Node) []Node {
+	v := reflect.ValueOf(node).Elem()
+	var children []Node
+	add := func(c reflect.Value) reflect.Value {
+		children = append(children, c.Interface().(Node))
+		return c
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		switch typ := field.Type(); {
+		case isNodeField(typ):
+			field.Set(add(newChild(typ)))
+		case typ.Kind() == reflect.Slice && isNodeField(typ.Elem()):
+			field.Set(reflect.MakeSlice(typ, 0, 2))
+			for j := 0; j < 2; j++ {
+				field.Set(reflect.Append(field, add(newChild(typ.Elem()))))
+			}
+		}
+	}
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if typ := field.Type(); typ.Kind() == reflect.Map {
+			field.Set(reflect.MakeMap(typ))
+			keys := v.FieldByName("Keys")
+			for j := 0; j < keys.Len(); j++ {
+				field.SetMapIndex(keys.Index(j), add(newChild(typ.Elem())))
+			}
+		}
+	}
+	return children
+}
+
+func visited(node Node) map[Node]bool {
+	seen := make(map[Node]bool)
+	Inspect(node, func(n Node) bool {
+		seen[n] = true
+		return true
+	})
+	return seen
+}
+
+func TestWalkVisitsAllChildren(t *testing.T) {
+	for _, n := range allNodes {
+		node := reflect.New(reflect.TypeOf(n).Elem()).Interface().(Node)
+		children := fill(node)
+
+		seen := visited(node)
+		if !seen[node] {
+			t.Errorf("%T: node not visited", node)
+		}
+		for _, c := range children {
+			if !seen[c] {
+				t.Errorf("%T: child %T not visited", node, c)
+			}
+		}
+	}
+}
+
+func TestModifyReplacesAllChildren(t *testing.T) {
+	for _, n := range allNodes {
+		node := reflect.New(reflect.TypeOf(n).Elem()).Interface().(Node)
+		children := fill(node)
+
+		// 把每个子节点替换为同类型的新节点
+		replaced := make(map[Node]Node)
+		for _, c := range children {
+			replaced[c] = reflect.New(reflect.TypeOf(c).Elem()).Interface().(Node)
+		}
+		Modify(node, func(n Node) Node {
+			if r, ok := replaced[n]; ok {
+				return r
+			}
+			return n
+		})
+
+		seen := visited(node)
+		for _, c := range children {
+			if seen[c] {
+				t.Errorf("%T: child %T not replaced", node, c)
+			}
+			if !seen[replaced[c]] {
+				t.Errorf("%T: replacement of %T not in tree", node, c)
+			}
+		}
+	}
+}
+
+func intLiteral(v int64) *IntegerLiteral {
+	return &IntegerLiteral{Token: mtoken.Token{Type: mtoken.INT}, Value: v}
+}
+
+func TestModify(t *testing.T) {
+	one, two := intLiteral(1), intLiteral(2)
+	hash := &HashLiteral{
+		Keys:  []Expression{one, two},
+		Pairs: map[Expression]Expression{one: intLiteral(10), two: intLiteral(20)},
+	}
+	program := &Program{Statements: []Statement{
+		&WhileStatement{
+			Condition:   intLiteral(3),
+			Consequence: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: hash}}},
+		},
+	}}
+
+	double := func(node Node) Node {
+		if i, ok := node.(*IntegerLiteral); ok {
+			return intLiteral(i.Value * 2)
+		}
+		return node
+	}
+	if Modify(program, double) != program {
+		t.Fatalf("Modify returned a different root")
+	}
+
+	var values []int64
+	Inspect(program, func(n Node) bool {
+		if i, ok := n.(*IntegerLiteral); ok {
+			values = append(values, i.Value)
+		}
+		return true
+	})
+	if !reflect.DeepEqual(values, []int64{6, 2, 20, 4, 40}) {
+		t.Errorf("wrong values. got=%v", values)
+	}
+	for _, k := range hash.Keys {
+		if _, ok := hash.Pairs[k]; !ok {
+			t.Errorf("key %v missing from Pairs", k.(*IntegerLiteral).Value)
+		}
+	}
+}
+
+func TestInspectSkipsChildren(t *testing.T) {
+	fn := &FunctionLiteral{
+		Parameters: []*Identifier{{Value: "x"}},
+		Body:       &BlockStatement{},
+	}
+	program := &Program{Statements: []Statement{
+		&ExpressionStatement{Expression: fn},
+		&ExpressionStatement{Expression: &Identifier{Value: "y"}},
+	}}
+
+	var names []string
+	Inspect(program, func(n Node) bool {
+		if id, ok := n.(*Identifier); ok {
+			names = append(names, id.Value)
+		}
+		_, isFn := n.(*FunctionLiteral)
+		return !isFn
+	})
+	if !reflect.DeepEqual(names, []string{"y"}) {
+		t.Errorf("wrong names. got=%v", names)
+	}
+}
+
+func TestTokenOf(t *testing.T) {
+	for i, n := range allNodes {
+		node := reflect.New(reflect.TypeOf(n).Elem()).Interface().(Node)
+		want := mtoken.Token{Line: i + 1}
+
+		// Program 没有 Token 字段，取第一条语句的 token
+		if p, ok := node.(*Program); ok {
+			p.Statements = []Statement{&ExpressionStatement{Token: want}}
+		} else {
+			reflect.ValueOf(node).Elem().FieldByName("Token").Set(reflect.ValueOf(want))
+		}
+
+		if got := TokenOf(node); got != want {
+			t.Errorf("%T: wrong token. want=%+v, got=%+v", node, want, got)
+		}
+	}
+	if got := TokenOf(&Program{}); got != (mtoken.Token{}) {
+		t.Errorf("empty Program: wrong token. got=%+v", got)
+	}
+}
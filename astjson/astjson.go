@@ -0,0 +1,222 @@
+// Package astjson 把语法树编码为 JSON，供 Go 以外的工具使用，并能从 JSON 重建语法树。
+//
+// 每个节点编码为一个对象，kind 是 ast 中的类型名，line 和 column 是节点 token 的位置，
+// children 按固定顺序列出子节点，可选的子节点不存在时为 null：
+//
+//	Program              语句...（另有 version、file、comments）
+//	LetStatement         Identifier, 值
+//	ExportStatement      LetStatement
+//	ReturnStatement      值
+//	ThrowStatement       值
+//	WhileStatement       条件, BlockStatement
+//	ExpressionStatement  表达式
+//	BlockStatement       语句...（另有 end，即右括号的位置）
+//	PrefixExpression     右操作数（另有 operator）
+//	InfixExpression      左操作数, 右操作数（另有 operator）
+//	IfExpression         条件, BlockStatement, else 的 BlockStatement 或 null
+//	TryExpression        BlockStatement, catch 的 Identifier 或 null, catch 的 BlockStatement 或 null, finally 的 BlockStatement 或 null
+//	CallExpression       函数, 参数...
+//	IndexExpression      对象, 下标（另有 optional）
+//	MemberExpression     对象, Identifier（另有 optional）
+//	FunctionLiteral      参数 Identifier..., BlockStatement（另有 name）
+//	ArrayLiteral         元素...
+//	HashLiteral          键, 值, 键, 值...
+//
+// Identifier、IntegerLiteral、StringLiteral、Boolean 和 ImportExpression 没有子节点，
+// 值在 value 中，NullLiteral 没有值。Program 没有位置，line 和 column 为 0。
+package astjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"shanyl2400/go_compiler/ast"
+	"shanyl2400/go_compiler/token"
+)
+
+// Version 是 JSON 格式的版本，格式发生不兼容的修改时增加
+const Version = 1
+
+// Node 是一个节点的 JSON 形式
+type Node struct {
+	Kind   string `json:"kind"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+
+	Value    json.RawMessage `json:"value,omitempty"`
+	Operator string          `json:"operator,omitempty"`
+	Name     string          `json:"name,omitempty"`
+	Optional bool            `json:"optional,omitempty"`
+	End      *Position       `json:"end,omitempty"`
+	Children []*Node         `json:"children,omitempty"`
+
+	// 以下字段只用于 Program
+	Version  int        `json:"version,omitempty"`
+	File     string     `json:"file,omitempty"`
+	Comments []*Comment `json:"comments,omitempty"`
+}
+
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type Comment struct {
+	Text     string `json:"text"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Trailing bool   `json:"trailing,omitempty"`
+}
+
+// Marshal 把程序编码为 JSON
+func Marshal(program *ast.Program) ([]byte, error) {
+	return json.Marshal(Encode(program))
+}
+
+// MarshalIndent 把程序编码为带缩进的 JSON
+func MarshalIndent(program *ast.Program, prefix, indent string) ([]byte, error) {
+	return json.MarshalIndent(Encode(program), prefix, indent)
+}
+
+// Unmarshal 从 JSON 重建程序
+func Unmarshal(data []byte) (*ast.Program, error) {
+	var n Node
+	if err := json.Unmarshal(data, &n); err != nil {
+		return nil, err
+	}
+	return Decode(&n)
+}
+
+// Encode 把程序转换为 Node
+func Encode(program *ast.Program) *Node {
+	n := &Node{Kind: "Program", Version: Version, File: program.File}
+	for _, stmt := range program.Statements {
+		n.Children = append(n.Children, encode(stmt))
+	}
+	for _, c := range program.Comments {
+		n.Comments = append(n.Comments, &Comment{
+			Text:     c.Text(),
+			Line:     c.Token.Line,
+			Column:   c.Token.Column,
+			Trailing: c.Trailing,
+		})
+	}
+	return n
+}
+
+func newNode(kind string, tok token.Token, children ...*Node) *Node {
+	return &Node{Kind: kind, Line: tok.Line, Column: tok.Column, Children: children}
+}
+
+func value(v any) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// encode 转换一个节点，nil 节点返回 nil
+func encode(node ast.Node) *Node {
+	switch node := node.(type) {
+	case *ast.LetStatement:
+		if node == nil {
+			return nil
+		}
+		return newNode("LetStatement", node.Token, encode(node.Name), encode(node.Value))
+	case *ast.ExportStatement:
+		return newNode("ExportStatement", node.Token, encode(node.Statement))
+	case *ast.ReturnStatement:
+		return newNode("ReturnStatement", node.Token, encode(node.Value))
+	case *ast.ThrowStatement:
+		return newNode("ThrowStatement", node.Token, encode(node.Value))
+	case *ast.WhileStatement:
+		return newNode("WhileStatement", node.Token, encode(node.Condition), encode(node.Consequence))
+	case *ast.ExpressionStatement:
+		return newNode("ExpressionStatement", node.Token, encode(node.Expression))
+	case *ast.BlockStatement:
+		if node == nil {
+			return nil
+		}
+		n := newNode("BlockStatement", node.Token)
+		n.End = &Position{Line: node.End.Line, Column: node.End.Column}
+		for _, stmt := range node.Statements {
+			n.Children = append(n.Children, encode(stmt))
+		}
+		return n
+	case *ast.Identifier:
+		if node == nil {
+			return nil
+		}
+		n := newNode("Identifier", node.Token)
+		n.Value = value(node.Value)
+		return n
+	case *ast.IntegerLiteral:
+		n := newNode("IntegerLiteral", node.Token)
+		n.Value = value(node.Value)
+		return n
+	case *ast.StringLiteral:
+		n := newNode("StringLiteral", node.Token)
+		n.Value = value(node.Value)
+		return n
+	case *ast.Boolean:
+		n := newNode("Boolean", node.Token)
+		n.Value = value(node.Value)
+		return n
+	case *ast.NullLiteral:
+		return newNode("NullLiteral", node.Token)
+	case *ast.PrefixExpression:
+		n := newNode("PrefixExpression", node.Token, encode(node.Right))
+		n.Operator = node.Operator
+		return n
+	case *ast.InfixExpression:
+		n := newNode("InfixExpression", node.Token, encode(node.Left), encode(node.Right))
+		n.Operator = node.Operator
+		return n
+	case *ast.IfExpression:
+		return newNode("IfExpression", node.Token, encode(node.Condition), encode(node.Consequence), encode(node.Alternative))
+	case *ast.TryExpression:
+		return newNode("TryExpression", node.Token, encode(node.Block), encode(node.Param), encode(node.Catch), encode(node.Finally))
+	case *ast.ImportExpression:
+		n := newNode("ImportExpression", node.Token)
+		n.Value = value(node.Path)
+		return n
+	case *ast.CallExpression:
+		n := newNode("CallExpression", node.Token, encode(node.Function))
+		for _, arg := range node.Arguments {
+			n.Children = append(n.Children, encode(arg))
+		}
+		return n
+	case *ast.IndexExpression:
+		n := newNode("IndexExpression", node.Token, encode(node.Left), encode(node.Index))
+		n.Optional = node.Optional
+		return n
+	case *ast.MemberExpression:
+		n := newNode("MemberExpression", node.Token, encode(node.Object), encode(node.Property))
+		n.Optional = node.Optional
+		return n
+	case *ast.FunctionLiteral:
+		n := newNode("FunctionLiteral", node.Token)
+		n.Name = node.Name
+		for _, param := range node.Parameters {
+			n.Children = append(n.Children, encode(param))
+		}
+		n.Children = append(n.Children, encode(node.Body))
+		return n
+	case *ast.ArrayLiteral:
+		n := newNode("ArrayLiteral", node.Token)
+		for _, elem := range node.Elements {
+			n.Children = append(n.Children, encode(elem))
+		}
+		return n
+	case *ast.HashLiteral:
+		n := newNode("HashLiteral", node.Token)
+		for _, key := range node.Keys {
+			n.Children = append(n.Children, encode(key), encode(node.Pairs[key]))
+		}
+		return n
+	case nil:
+		return nil
+	default:
+		panic(fmt.Sprintf("astjson: unknown node type %T", node))
+	}
+}
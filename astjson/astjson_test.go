@@ -0,0 +1,188 @@
+package astjson
+
+import (
+	"io"
+	"reflect"
+	"shanyl2400/go_compiler/ast"
+	"shanyl2400/go_compiler/evaluator"
+	"shanyl2400/go_compiler/lexer"
+	"shanyl2400/go_compiler/object"
+	"shanyl2400/go_compiler/parser"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// source 包含所有种类的节点
+const source = `// 所有节点
+let add = fn(a, b) { return a + b; };
+let h = {"name": "monkey", 1: [true, false, null]};
+let i = 0;
+while (i < 3) {
+    let i = i + 1;
+}
+let r = try {
+    throw "boom";
+} catch (e) {
+    e.message
+} finally {
+    puts(-i, !true) // 结尾注释
+};
+let n = h?.missing ?? h["name"];
+let m = h?["name"];
+if (add(1, 2) == 3) { r } else { n };
+`
+
+func parse(t *testing.T, src string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	require.Empty(t, p.Errors())
+	return program
+}
+
+func kinds(n *Node, seen map[string]bool) {
+	if n == nil {
+		return
+	}
+	seen[n.Kind] = true
+	for _, c := range n.Children {
+		kinds(c, seen)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	programs := []string{
+		source,
+		"import \"lib\";\nexport let x = 1;\n",
+		"fn() { 1 }();\n(1 + 2) * 3;\n",
+		"",
+	}
+
+	for _, src := range programs {
+		program := parse(t, src)
+		program.File = "test.mk"
+
+		data, err := Marshal(program)
+		require.NoError(t, err)
+		decoded, err := Unmarshal(data)
+		require.NoError(t, err)
+
+		assert.Equal(t, program.String(), decoded.String())
+		assert.Equal(t, "test.mk", decoded.File)
+		assert.Equal(t, program.Comments, decoded.Comments)
+		// 再次编码的结果相同，说明位置和值都保留了
+		again, err := Marshal(decoded)
+		require.NoError(t, err)
+		assert.JSONEq(t, string(data), string(again))
+	}
+}
+
+func TestAllKinds(t *testing.T) {
+	seen := make(map[string]bool)
+	kinds(Encode(parse(t, source+"import \"lib\";\nexport let x = 1;\n")), seen)
+
+	var got []string
+	for kind := range seen {
+		got = append(got, kind)
+	}
+	sort.Strings(got)
+	assert.Equal(t, []string{
+		"ArrayLiteral", "BlockStatement", "Boolean", "CallExpression", "ExportStatement",
+		"ExpressionStatement", "FunctionLiteral", "HashLiteral", "Identifier", "IfExpression",
+		"ImportExpression", "IndexExpression", "InfixExpression", "IntegerLiteral", "LetStatement",
+		"MemberExpression", "NullLiteral", "PrefixExpression", "Program", "ReturnStatement",
+		"StringLiteral", "ThrowStatement", "TryExpression", "WhileStatement",
+	}, got)
+}
+
+func TestDecodedProgramRuns(t *testing.T) {
+	program := parse(t, source)
+	data, err := Marshal(program)
+	require.NoError(t, err)
+	decoded, err := Unmarshal(data)
+	require.NoError(t, err)
+
+	run := func(program *ast.Program) string {
+		e := evaluator.New(object.NewContext(io.Discard, io.Discard))
+		return e.Eval(program, object.NewEnvironment()).Inspect()
+	}
+	assert.Equal(t, run(program), run(decoded))
+
+	// 语句的位置用于调用栈
+	let := decoded.Statements[0].(*ast.LetStatement)
+	assert.Equal(t, 2, let.Token.Line)
+	assert.Equal(t, 1, let.Token.Column)
+}
+
+func TestSchema(t *testing.T) {
+	data, err := Marshal(parse(t, "let x = -1;\nf(x)[0];"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"kind": "Program", "line": 0, "column": 0, "version": 1,
+		"children": [
+			{"kind": "LetStatement", "line": 1, "column": 1, "children": [
+				{"kind": "Identifier", "line": 1, "column": 5, "value": "x"},
+				{"kind": "PrefixExpression", "line": 1, "column": 9, "operator": "-", "children": [
+					{"kind": "IntegerLiteral", "line": 1, "column": 10, "value": 1}
+				]}
+			]},
+			{"kind": "ExpressionStatement", "line": 2, "column": 1, "children": [
+				{"kind": "IndexExpression", "line": 2, "column": 5, "children": [
+					{"kind": "CallExpression", "line": 2, "column": 2, "children": [
+						{"kind": "Identifier", "line": 2, "column": 1, "value": "f"},
+						{"kind": "Identifier", "line": 2, "column": 3, "value": "x"}
+					]},
+					{"kind": "IntegerLiteral", "line": 2, "column": 6, "value": 0}
+				]}
+			]}
+		]
+	}`, string(data))
+}
+
+func TestDecodeTokens(t *testing.T) {
+	program := parse(t, "let x = a != b;\nx ?? y;\n")
+	data, err := Marshal(program)
+	require.NoError(t, err)
+	decoded, err := Unmarshal(data)
+	require.NoError(t, err)
+
+	// 没有 HashLiteral 和括号时，重建的语法树与解析的完全相同
+	assert.True(t, reflect.DeepEqual(program, decoded))
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"kind": "LetStatement"}`, "expected Program node"},
+		{`{"kind": "Program", "version": 2}`, "unsupported version 2"},
+		{`{"kind": "Program", "children": [null]}`, "Program at 0:0: missing child 0"},
+		{`{"kind": "Program", "children": [{"kind": "Nope", "line": 1, "column": 2}]}`, "Nope at 1:2: not a statement"},
+		{`{"kind": "Program", "children": [{"kind": "ExpressionStatement", "children": [{"kind": "BlockStatement"}]}]}`,
+			"BlockStatement at 0:0: not an expression"},
+		{`{"kind": "Program", "children": [{"kind": "LetStatement", "children": [{"kind": "Identifier", "value": "x"}]}]}`,
+			"LetStatement at 0:0: expected 2 children, got 1"},
+		{`{"kind": "Program", "children": [{"kind": "ExpressionStatement", "children": [{"kind": "IntegerLiteral", "value": "1"}]}]}`,
+			"IntegerLiteral at 0:0: invalid value"},
+		{`{"kind": "Program", "children": [{"kind": "ExpressionStatement", "children": [
+			{"kind": "PrefixExpression", "operator": "~", "children": [{"kind": "NullLiteral"}]}]}]}`,
+			`PrefixExpression at 0:0: invalid operator "~"`},
+		{`{"kind": "Program", "children": [{"kind": "ExpressionStatement", "children": [
+			{"kind": "HashLiteral", "children": [{"kind": "NullLiteral"}]}]}]}`,
+			"HashLiteral at 0:0: expected key and value pairs, got 1 children"},
+		{`{"kind": "Program", "children": [{"kind": "ExpressionStatement", "children": [
+			{"kind": "TryExpression", "children": [{"kind": "BlockStatement"}, null, null, null]}]}]}`,
+			"TryExpression at 0:0: expected catch or finally"},
+	}
+
+	for _, tt := range tests {
+		_, err := Unmarshal([]byte(tt.input))
+		if assert.Error(t, err, tt.input) {
+			assert.Contains(t, err.Error(), tt.expected)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package astjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"shanyl2400/go_compiler/ast"
+	"shanyl2400/go_compiler/lexer"
+	"shanyl2400/go_compiler/token"
+	"strconv"
+)
+
+// decodeError 在解码的递归中用 panic 传递，由 Decode 恢复
+type decodeError struct {
+	err error
+}
+
+func fail(n *Node, format string, a ...any) {
+	panic(decodeError{fmt.Errorf("%s at %d:%d: %s", n.Kind, n.Line, n.Column, fmt.Sprintf(format, a...))})
+}
+
+// Decode 从 Node 重建程序，节点的种类、子节点或值不符合格式时返回错误。
+// 节点的 token 根据种类和值重建，位置与编码时相同。
+func Decode(n *Node) (program *ast.Program, err error) {
+	if n == nil || n.Kind != "Program" {
+		return nil, fmt.Errorf("expected Program node")
+	}
+	if n.Version > Version {
+		return nil, fmt.Errorf("unsupported version %d", n.Version)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			de, ok := r.(decodeError)
+			if !ok {
+				panic(r)
+			}
+			program, err = nil, de.err
+		}
+	}()
+
+	program = &ast.Program{File: n.File, Statements: []ast.Statement{}}
+	for i := range n.Children {
+		program.Statements = append(program.Statements, decodeStatement(child(n, i)))
+	}
+	for _, c := range n.Comments {
+		program.Comments = append(program.Comments, &ast.Comment{
+			Token:    token.Token{Type: token.COMMENT, Literal: c.Text, Line: c.Line, Column: c.Column},
+			Trailing: c.Trailing,
+		})
+	}
+	return program, nil
+}
+
+func newToken(n *Node, tokenType token.TokenType, literal string) token.Token {
+	return token.Token{Type: tokenType, Literal: literal, Line: n.Line, Column: n.Column}
+}
+
+// expectChildren 检查子节点的数量
+func expectChildren(n *Node, count int) {
+	if len(n.Children) != count {
+		fail(n, "expected %d children, got %d", count, len(n.Children))
+	}
+}
+
+// child 返回第 i 个子节点，不能为 null
+func child(n *Node, i int) *Node {
+	if i >= len(n.Children) || n.Children[i] == nil {
+		fail(n, "missing child %d", i)
+	}
+	return n.Children[i]
+}
+
+func decodeValue(n *Node, v any) {
+	if err := json.Unmarshal(n.Value, v); err != nil {
+		fail(n, "invalid value: %v", err)
+	}
+}
+
+// operatorToken 用词法分析器得到运算符的 token 类型
+func operatorToken(n *Node) token.Token {
+	tok := lexer.New(n.Operator).NextToken()
+	if tok.Literal != n.Operator || tok.Type == token.ILLEGAL {
+		fail(n, "invalid operator %q", n.Operator)
+	}
+	return newToken(n, tok.Type, tok.Literal)
+}
+
+func decodeStatement(n *Node) ast.Statement {
+	switch n.Kind {
+	case "LetStatement":
+		return decodeLet(n)
+	case "ExportStatement":
+		expectChildren(n, 1)
+		let := child(n, 0)
+		if let.Kind != "LetStatement" {
+			fail(n, "expected LetStatement, got %s", let.Kind)
+		}
+		return &ast.ExportStatement{Token: newToken(n, token.EXPORT, "export"), Statement: decodeLet(let)}
+	case "ReturnStatement":
+		expectChildren(n, 1)
+		return &ast.ReturnStatement{Token: newToken(n, token.RETURN, "return"), Value: decodeExpression(child(n, 0))}
+	case "ThrowStatement":
+		expectChildren(n, 1)
+		return &ast.ThrowStatement{Token: newToken(n, token.THROW, "throw"), Value: decodeExpression(child(n, 0))}
+	case "WhileStatement":
+		expectChildren(n, 2)
+		return &ast.WhileStatement{
+			Token:       newToken(n, token.WHILE, "while"),
+			Condition:   decodeExpression(child(n, 0)),
+			Consequence: decodeBlock(child(n, 1)),
+		}
+	case "ExpressionStatement":
+		expectChildren(n, 1)
+		exp := decodeExpression(child(n, 0))
+		// 语句的 token 是表达式的第一个 token，括号不在语法树中，这里使用最左边的子表达式
+		tok := firstToken(exp)
+		tok.Line, tok.Column = n.Line, n.Column
+		return &ast.ExpressionStatement{Token: tok, Expression: exp}
+	case "BlockStatement":
+		return decodeBlock(n)
+	}
+	fail(n, "not a statement")
+	return nil
+}
+
+func decodeLet(n *Node) *ast.LetStatement {
+	expectChildren(n, 2)
+	return &ast.LetStatement{
+		Token: newToken(n, token.LET, "let"),
+		Name:  decodeIdentifier(child(n, 0)),
+		Value: decodeExpression(child(n, 1)),
+	}
+}
+
+func decodeBlock(n *Node) *ast.BlockStatement {
+	if n.Kind != "BlockStatement" {
+		fail(n, "expected BlockStatement")
+	}
+	block := &ast.BlockStatement{Token: newToken(n, token.LBRACE, "{"), Statements: []ast.Statement{}}
+	if n.End != nil {
+		block.End = token.Token{Type: token.RBRACE, Literal: "}", Line: n.End.Line, Column: n.End.Column}
+	}
+	for i := range n.Children {
+		block.Statements = append(block.Statements, decodeStatement(child(n, i)))
+	}
+	return block
+}
+
+// optionalBlock 解码可以为 null 的 BlockStatement
+func optionalBlock(n *Node) *ast.BlockStatement {
+	if n == nil {
+		return nil
+	}
+	return decodeBlock(n)
+}
+
+func decodeIdentifier(n *Node) *ast.Identifier {
+	if n.Kind != "Identifier" {
+		fail(n, "expected Identifier")
+	}
+	var name string
+	decodeValue(n, &name)
+	return &ast.Identifier{Token: newToken(n, token.IDENT, name), Value: name}
+}
+
+func decodeExpressions(n *Node, from int) []ast.Expression {
+	exps := []ast.Expression{}
+	for i := from; i < len(n.Children); i++ {
+		exps = append(exps, decodeExpression(child(n, i)))
+	}
+	return exps
+}
+
+func decodeExpression(n *Node) ast.Expression {
+	switch n.Kind {
+	case "Identifier":
+		return decodeIdentifier(n)
+	case "IntegerLiteral":
+		var v int64
+		decodeValue(n, &v)
+		return &ast.IntegerLiteral{Token: newToken(n, token.INT, strconv.FormatInt(v, 10)), Value: v}
+	case "StringLiteral":
+		var v string
+		decodeValue(n, &v)
+		return &ast.StringLiteral{Token: newToken(n, token.STRING, v), Value: v}
+	case "Boolean":
+		var v bool
+		decodeValue(n, &v)
+		if v {
+			return &ast.Boolean{Token: newToken(n, token.TRUE, "true"), Value: v}
+		}
+		return &ast.Boolean{Token: newToken(n, token.FALSE, "false"), Value: v}
+	case "NullLiteral":
+		return &ast.NullLiteral{Token: newToken(n, token.NULL, "null")}
+	case "PrefixExpression":
+		expectChildren(n, 1)
+		return &ast.PrefixExpression{Token: operatorToken(n), Operator: n.Operator, Right: decodeExpression(child(n, 0))}
+	case "InfixExpression":
+		expectChildren(n, 2)
+		return &ast.InfixExpression{
+			Token:    operatorToken(n),
+			Operator: n.Operator,
+			Left:     decodeExpression(child(n, 0)),
+			Right:    decodeExpression(child(n, 1)),
+		}
+	case "IfExpression":
+		expectChildren(n, 3)
+		return &ast.IfExpression{
+			Token:       newToken(n, token.IF, "if"),
+			Condition:   decodeExpression(child(n, 0)),
+			Consequence: decodeBlock(child(n, 1)),
+			Alternative: optionalBlock(n.Children[2]),
+		}
+	case "TryExpression":
+		expectChildren(n, 4)
+		exp := &ast.TryExpression{
+			Token:   newToken(n, token.TRY, "try"),
+			Block:   decodeBlock(child(n, 0)),
+			Catch:   optionalBlock(n.Children[2]),
+			Finally: optionalBlock(n.Children[3]),
+		}
+		if n.Children[1] != nil {
+			exp.Param = decodeIdentifier(n.Children[1])
+		}
+		if exp.Catch == nil && exp.Finally == nil {
+			fail(n, "expected catch or finally")
+		}
+		return exp
+	case "ImportExpression":
+		var path string
+		decodeValue(n, &path)
+		return &ast.ImportExpression{Token: newToken(n, token.IMPORT, "import"), Path: path}
+	case "CallExpression":
+		return &ast.CallExpression{
+			Token:     newToken(n, token.LPAREN, "("),
+			Function:  decodeExpression(child(n, 0)),
+			Arguments: decodeExpressions(n, 1),
+		}
+	case "IndexExpression":
+		expectChildren(n, 2)
+		tok := newToken(n, token.LBRACKET, "[")
+		if n.Optional {
+			tok = newToken(n, token.OPT_LBRACKET, "?[")
+		}
+		return &ast.IndexExpression{
+			Token:    tok,
+			Left:     decodeExpression(child(n, 0)),
+			Index:    decodeExpression(child(n, 1)),
+			Optional: n.Optional,
+		}
+	case "MemberExpression":
+		expectChildren(n, 2)
+		tok := newToken(n, token.DOT, ".")
+		if n.Optional {
+			tok = newToken(n, token.OPT_DOT, "?.")
+		}
+		return &ast.MemberExpression{
+			Token:    tok,
+			Object:   decodeExpression(child(n, 0)),
+			Property: decodeIdentifier(child(n, 1)),
+			Optional: n.Optional,
+		}
+	case "FunctionLiteral":
+		last := len(n.Children) - 1
+		fn := &ast.FunctionLiteral{
+			Token:      newToken(n, token.FUNCTION, "fn"),
+			Parameters: []*ast.Identifier{},
+			Body:       decodeBlock(child(n, last)),
+			Name:       n.Name,
+		}
+		for i := 0; i < last; i++ {
+			fn.Parameters = append(fn.Parameters, decodeIdentifier(child(n, i)))
+		}
+		return fn
+	case "ArrayLiteral":
+		return &ast.ArrayLiteral{Token: newToken(n, token.LBRACKET, "["), Elements: decodeExpressions(n, 0)}
+	case "HashLiteral":
+		if len(n.Children)%2 != 0 {
+			fail(n, "expected key and value pairs, got %d children", len(n.Children))
+		}
+		hash := &ast.HashLiteral{
+			Token: newToken(n, token.LBRACE, "{"),
+			Pairs: make(map[ast.Expression]ast.Expression),
+		}
+		for i := 0; i < len(n.Children); i += 2 {
+			key := decodeExpression(child(n, i))
+			hash.Keys = append(hash.Keys, key)
+			hash.Pairs[key] = decodeExpression(child(n, i+1))
+		}
+		return hash
+	}
+	fail(n, "not an expression")
+	return nil
+}
+
+// firstToken 返回表达式最左边的子表达式的 token
+func firstToken(exp ast.Expression) token.Token {
+	switch exp := exp.(type) {
+	case *ast.InfixExpression:
+		return firstToken(exp.Left)
+	case *ast.CallExpression:
+		return firstToken(exp.Function)
+	case *ast.IndexExpression:
+		return firstToken(exp.Left)
+	case *ast.MemberExpression:
+		return firstToken(exp.Object)
+	}
+	return ast.TokenOf(exp)
+}
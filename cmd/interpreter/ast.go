@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"shanyl2400/go_compiler/ast"
+	"shanyl2400/go_compiler/astjson"
+	"shanyl2400/go_compiler/lexer"
+	"shanyl2400/go_compiler/parser"
+)
+
+// runAST 实现 interpreter ast 命令，打印语法树，没有指定文件时读取标准输入
+func runAST(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("ast", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	asJSON := flags.Bool("json", false, "print the tree as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 1 {
+		fmt.Fprintln(stderr, "usage: interpreter ast [-json] [file]")
+		return 2
+	}
+
+	path := "<stdin>"
+	var src []byte
+	var err error
+	if flags.NArg() == 0 {
+		src, err = io.ReadAll(stdin)
+	} else {
+		path = flags.Arg(0)
+		src, err = os.ReadFile(path)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		fmt.Fprintf(stderr, "%s: parser errors:\n", path)
+		for _, msg := range p.Errors() {
+			fmt.Fprintf(stderr, "\t%s\n", msg)
+		}
+		return 1
+	}
+	if flags.NArg() != 0 {
+		program.File = path
+	}
+
+	if !*asJSON {
+		ast.Fprint(stdout, program)
+		return 0
+	}
+	data, err := astjson.MarshalIndent(program, "", "  ")
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	stdout.Write(append(data, '\n'))
+	return 0
+}
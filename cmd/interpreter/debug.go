@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"shanyl2400/go_compiler/debugger"
+	"shanyl2400/go_compiler/evaluator"
+	"shanyl2400/go_compiler/lexer"
+	"shanyl2400/go_compiler/object"
+	"shanyl2400/go_compiler/parser"
+)
+
+// runDebug 实现 interpreter debug 命令，在第一条语句暂停并从 stdin 读取调试命令
+func runDebug(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) != 1 {
+		fmt.Fprintln(stderr, "usage: interpreter debug file")
+		return 2
+	}
+	path := args[0]
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		fmt.Fprintf(stderr, "%s: parser errors:\n", path)
+		for _, msg := range p.Errors() {
+			fmt.Fprintf(stderr, "\t%s\n", msg)
+		}
+		return 1
+	}
+	program.File = path
+
+	e := evaluator.New(object.NewContext(stdout, stderr))
+	if dirs := os.Getenv(pathEnv); dirs != "" {
+		e.SetSearchPath(filepath.SplitList(dirs)...)
+	}
+	debugger.New(e, string(src), stdin, stdout)
+
+	evaluated := e.Eval(program, object.NewEnvironment())
+	if errObj, ok := evaluated.(*object.Error); ok {
+		if errors.Is(errObj.Err, debugger.ErrQuit) {
+			return 0
+		}
+		fmt.Fprintln(stderr, errObj.Inspect())
+		fmt.Fprint(stderr, errObj.StackTrace())
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"shanyl2400/go_compiler/lexer"
+	"shanyl2400/go_compiler/lint"
+	"shanyl2400/go_compiler/parser"
+	"strings"
+)
+
+// runLint 实现 interpreter lint 命令，有问题时返回 1
+func runLint(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	enabled := flags.String("rules", "", "comma-separated rules to run (default all)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	rules, err := selectRules(*enabled)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(stderr, "usage: interpreter lint [-rules name,...] file...")
+		return 2
+	}
+
+	code := 0
+	for _, path := range flags.Args() {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			code = 1
+			continue
+		}
+
+		p := parser.New(lexer.New(string(src)))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			for _, msg := range p.Errors() {
+				fmt.Fprintf(stdout, "%s: %s\n", path, msg)
+			}
+			code = 1
+			continue
+		}
+
+		for _, d := range lint.Run(program, rules...) {
+			fmt.Fprintf(stdout, "%s:%s\n", path, d)
+			code = 1
+		}
+	}
+	return code
+}
+
+func selectRules(names string) ([]lint.Rule, error) {
+	if names == "" {
+		return lint.DefaultRules, nil
+	}
+
+	var rules []lint.Rule
+	for _, name := range strings.Split(names, ",") {
+		found := false
+		for _, rule := range lint.DefaultRules {
+			if rule.Name() == strings.TrimSpace(name) {
+				rules = append(rules, rule)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown rule: %s", name)
+		}
+	}
+	return rules, nil
+}
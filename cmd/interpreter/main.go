@@ -4,10 +4,44 @@ import (
 	"fmt"
 	"os"
 	"os/user"
+	"path/filepath"
+	"shanyl2400/go_compiler/dap"
+	"shanyl2400/go_compiler/lsp"
 	"shanyl2400/go_compiler/repl"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "fmt":
+			os.Exit(runFmt(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+		case "lint":
+			os.Exit(runLint(os.Args[2:], os.Stdout, os.Stderr))
+		case "ast":
+			os.Exit(runAST(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+		case "debug":
+			os.Exit(runDebug(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+		case "dap":
+			s := dap.NewServer(os.Stdin, os.Stdout)
+			if dirs := os.Getenv(pathEnv); dirs != "" {
+				s.SetSearchPath(filepath.SplitList(dirs)...)
+			}
+			if err := s.Run(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		case "lsp":
+			if err := lsp.Serve(os.Stdin, os.Stdout); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		default:
+			os.Exit(runFile(os.Args[1], os.Stdout, os.Stderr))
+		}
+	}
+
 	usr, err := user.Current()
 	if err != nil {
 		panic(err)
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"shanyl2400/go_compiler/evaluator"
+	"shanyl2400/go_compiler/lexer"
+	"shanyl2400/go_compiler/object"
+	"shanyl2400/go_compiler/parser"
+)
+
+// pathEnv 是 import 搜索路径的环境变量，多个目录用系统的路径分隔符分开
+const pathEnv = "MONKEY_PATH"
+
+// runFile 执行脚本文件，返回进程的退出码
+func runFile(path string, stdout, stderr io.Writer) int {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		fmt.Fprintf(stderr, "%s: parser errors:\n", path)
+		for _, msg := range p.Errors() {
+			fmt.Fprintf(stderr, "\t%s\n", msg)
+		}
+		return 1
+	}
+	program.File = path
+
+	e := evaluator.New(object.NewContext(stdout, stderr))
+	if dirs := os.Getenv(pathEnv); dirs != "" {
+		e.SetSearchPath(filepath.SplitList(dirs)...)
+	}
+	evaluated := e.Eval(program, object.NewEnvironment())
+	if errObj, ok := evaluated.(*object.Error); ok {
+		fmt.Fprintln(stderr, errObj.Inspect())
+		fmt.Fprint(stderr, errObj.StackTrace())
+		return 1
+	}
+	return 0
+}
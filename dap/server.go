@@ -0,0 +1,568 @@
+// Package dap 实现通过标准输入输出通信的 Debug Adapter Protocol 服务器
+package dap
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"shanyl2400/go_compiler/ast"
+	"shanyl2400/go_compiler/evaluator"
+	"shanyl2400/go_compiler/internal/framing"
+	"shanyl2400/go_compiler/lexer"
+	"shanyl2400/go_compiler/object"
+	"shanyl2400/go_compiler/parser"
+	"strings"
+	"sync"
+)
+
+// threadID 是唯一线程的 id，Monkey 程序只有一个线程
+const threadID = 1
+
+// errTerminated 客户端结束调试时中止程序
+var errTerminated = errors.New("debuggee terminated")
+
+type mode int
+
+const (
+	modeContinue mode = iota
+	modeStepIn
+	modeStepOver
+	modeStepOut
+)
+
+// Server 在一个 goroutine 中处理请求，被调试的程序在另一个 goroutine 中执行
+type Server struct {
+	in         *bufio.Reader
+	out        io.Writer
+	searchPath []string
+
+	// after 在回复当前请求之后执行，用于发送事件和恢复执行
+	after func()
+
+	launch     LaunchArguments
+	program    *ast.Program
+	configured bool
+
+	eval   *evaluator.Evaluator
+	ctx    context.Context
+	cancel context.CancelFunc
+	resume chan struct{}
+	done   chan struct{}
+
+	// envs[i] 是第 i 层调用最近执行的语句所在的环境，只在程序的 goroutine 中修改
+	envs []*object.Environment
+
+	// mu 保护下面的字段
+	mu  sync.Mutex
+	seq int
+	// err 是写入消息时的错误，Run 发现后退出
+	err error
+	// breakpoints 按文件的绝对路径保存断点所在的行
+	breakpoints map[string]map[int]bool
+	mode        mode
+	reason      string
+	// depth 是发出 next 或 stepOut 请求时的调用深度
+	depth   int
+	stopped *stopState
+}
+
+// stopState 是程序暂停时的状态，恢复执行后失效
+type stopState struct {
+	// frames 和 envs 最内层在前
+	frames []object.StackFrame
+	envs   []*object.Environment
+	// handles 是 variablesReference 减一对应的环境或复合值
+	handles []any
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:          bufio.NewReader(in),
+		out:         out,
+		breakpoints: make(map[string]map[int]bool),
+	}
+}
+
+// Serve 处理请求直到收到 disconnect 请求或输入结束
+func Serve(in io.Reader, out io.Writer) error {
+	return NewServer(in, out).Run()
+}
+
+// SetSearchPath 设置被调试程序的 import 搜索路径
+func (s *Server) SetSearchPath(dirs ...string) {
+	s.searchPath = dirs
+}
+
+func (s *Server) Run() error {
+	defer s.terminate()
+	for {
+		body, err := framing.Read(s.in)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// 无法解析的请求回复错误后继续处理后面的请求
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			s.send(&response{Type: "response", Success: false, Message: err.Error()})
+			if err := s.writeErr(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		result, herr := s.handle(&req)
+		resp := response{Type: "response", RequestSeq: req.Seq, Success: herr == nil, Command: req.Command, Body: result}
+		if herr != nil {
+			resp.Message = herr.Error()
+		}
+		s.send(&resp)
+		if s.after != nil {
+			s.after()
+			s.after = nil
+		}
+		if err := s.writeErr(); err != nil {
+			return err
+		}
+		if req.Command == "disconnect" {
+			return nil
+		}
+	}
+}
+
+// send 设置序号并写入消息，可以在程序的 goroutine 中调用
+func (s *Server) send(msg any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	switch msg := msg.(type) {
+	case *response:
+		msg.Seq = s.seq
+	case *event:
+		msg.Seq = s.seq
+	}
+	if s.err == nil {
+		s.err = framing.Write(s.out, msg)
+	}
+}
+
+func (s *Server) sendEvent(name string, body any) {
+	s.send(&event{Type: "event", Event: name, Body: body})
+}
+
+func (s *Server) writeErr() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+func (s *Server) handle(req *request) (any, error) {
+	switch req.Command {
+	case "initialize":
+		s.after = func() { s.sendEvent("initialized", nil) }
+		return Capabilities{
+			SupportsConfigurationDoneRequest: true,
+			SupportsEvaluateForHovers:        true,
+			SupportsTerminateRequest:         true,
+		}, nil
+	case "launch":
+		if err := unmarshalArguments(req, &s.launch); err != nil {
+			return nil, err
+		}
+		if err := s.load(s.launch.Program); err != nil {
+			return nil, err
+		}
+		s.after = s.start
+		return nil, nil
+	case "configurationDone":
+		s.configured = true
+		s.after = s.start
+		return nil, nil
+	case "setBreakpoints":
+		var args SetBreakpointsArguments
+		if err := unmarshalArguments(req, &args); err != nil {
+			return nil, err
+		}
+		return s.setBreakpoints(&args)
+	case "threads":
+		return ThreadsResponseBody{Threads: []Thread{{ID: threadID, Name: "main"}}}, nil
+	case "stackTrace":
+		return s.stackTrace()
+	case "scopes":
+		var args ScopesArguments
+		if err := unmarshalArguments(req, &args); err != nil {
+			return nil, err
+		}
+		return s.scopes(args.FrameID)
+	case "variables":
+		var args VariablesArguments
+		if err := unmarshalArguments(req, &args); err != nil {
+			return nil, err
+		}
+		return s.variables(args.VariablesReference)
+	case "evaluate":
+		var args EvaluateArguments
+		if err := unmarshalArguments(req, &args); err != nil {
+			return nil, err
+		}
+		return s.evaluate(&args)
+	case "continue":
+		return ContinueResponseBody{AllThreadsContinued: true}, s.resumeWith(modeContinue)
+	case "next":
+		return nil, s.resumeWith(modeStepOver)
+	case "stepIn":
+		return nil, s.resumeWith(modeStepIn)
+	case "stepOut":
+		return nil, s.resumeWith(modeStepOut)
+	case "pause":
+		s.mu.Lock()
+		if s.stopped == nil {
+			s.mode, s.reason = modeStepIn, "pause"
+		}
+		s.mu.Unlock()
+		return nil, nil
+	case "terminate", "disconnect":
+		s.terminate()
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unsupported request: %s", req.Command)
+	}
+}
+
+func unmarshalArguments(req *request, v any) error {
+	if err := json.Unmarshal(req.Arguments, v); err != nil {
+		return fmt.Errorf("invalid arguments for %s: %v", req.Command, err)
+	}
+	return nil
+}
+
+// load 读取并解析要调试的程序
+func (s *Server) load(path string) error {
+	if s.program != nil {
+		return errors.New("program already launched")
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return fmt.Errorf("%s: parser errors:\n\t%s", path, strings.Join(p.Errors(), "\n\t"))
+	}
+	program.File = path
+	s.program = program
+	return nil
+}
+
+// start 在 launch 和 configurationDone 都完成后开始执行程序
+func (s *Server) start() {
+	if s.program == nil || !s.configured || s.done != nil {
+		return
+	}
+
+	e := evaluator.New(object.NewContext(&output{s, "stdout"}, &output{s, "stderr"}))
+	if len(s.searchPath) != 0 {
+		e.SetSearchPath(s.searchPath...)
+	}
+	if !s.launch.NoDebug {
+		e.SetDebugHook(s.hook)
+	}
+	if s.launch.StopOnEntry {
+		s.mode, s.reason = modeStepIn, "entry"
+	}
+
+	s.eval = e
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.resume = make(chan struct{})
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+
+		exitCode := 0
+		evaluated := e.EvalContext(s.ctx, s.program, object.NewEnvironment())
+		if errObj, ok := evaluated.(*object.Error); ok && !errors.Is(errObj.Err, errTerminated) && !errors.Is(errObj.Err, context.Canceled) {
+			s.sendEvent("output", OutputEventBody{Category: "stderr", Output: errObj.Inspect() + "\n" + errObj.StackTrace()})
+			exitCode = 1
+		}
+		s.sendEvent("exited", ExitedEventBody{ExitCode: exitCode})
+		s.sendEvent("terminated", nil)
+	}()
+}
+
+// terminate 中止正在执行的程序并等待它结束
+func (s *Server) terminate() {
+	if s.done == nil {
+		return
+	}
+	s.cancel()
+	<-s.done
+}
+
+// output 把程序的输出作为 output 事件发送
+type output struct {
+	s        *Server
+	category string
+}
+
+func (o *output) Write(p []byte) (int, error) {
+	o.s.sendEvent("output", OutputEventBody{Category: o.category, Output: string(p)})
+	return len(p), nil
+}
+
+func (s *Server) setBreakpoints(args *SetBreakpointsArguments) (any, error) {
+	path, err := filepath.Abs(args.Source.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make(map[int]bool)
+	result := SetBreakpointsResponseBody{Breakpoints: []Breakpoint{}}
+	for _, bp := range args.Breakpoints {
+		lines[bp.Line] = true
+		result.Breakpoints = append(result.Breakpoints, Breakpoint{Verified: true, Line: bp.Line})
+	}
+
+	s.mu.Lock()
+	s.breakpoints[path] = lines
+	s.mu.Unlock()
+	return result, nil
+}
+
+// hook 在每条语句执行前调用，需要暂停时发送 stopped 事件并等待恢复执行
+func (s *Server) hook(stmt ast.Statement, env *object.Environment) error {
+	stack := s.eval.Stack()
+	depth := len(stack)
+	for len(s.envs) < depth {
+		s.envs = append(s.envs, nil)
+	}
+	s.envs = s.envs[:depth]
+	s.envs[depth-1] = env
+
+	s.mu.Lock()
+	var reason string
+	switch s.mode {
+	case modeStepIn:
+		reason = s.reason
+	case modeStepOver:
+		if depth <= s.depth {
+			reason = s.reason
+		}
+	case modeStepOut:
+		if depth < s.depth {
+			reason = s.reason
+		}
+	}
+	frame := stack[0]
+	if s.breakpoints[frame.File][frame.Line] {
+		reason = "breakpoint"
+	}
+	if reason == "" {
+		s.mu.Unlock()
+		return nil
+	}
+
+	envs := make([]*object.Environment, depth)
+	for i, env := range s.envs {
+		envs[depth-1-i] = env
+	}
+	s.mode = modeContinue
+	s.stopped = &stopState{frames: stack, envs: envs}
+	s.mu.Unlock()
+
+	s.sendEvent("stopped", StoppedEventBody{Reason: reason, ThreadID: threadID, AllThreadsStopped: true})
+	select {
+	case <-s.resume:
+		return nil
+	case <-s.ctx.Done():
+		return errTerminated
+	}
+}
+
+// resumeWith 设置单步方式，回复请求后恢复执行
+func (s *Server) resumeWith(m mode) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped == nil {
+		return errors.New("program is not stopped")
+	}
+	s.mode, s.reason = m, "step"
+	s.depth = len(s.stopped.frames)
+	s.stopped = nil
+	s.after = func() { s.resume <- struct{}{} }
+	return nil
+}
+
+// pausedState 返回暂停时的状态，程序在执行时返回错误
+func (s *Server) pausedState() (*stopState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped == nil {
+		return nil, errors.New("program is not stopped")
+	}
+	return s.stopped, nil
+}
+
+func (s *Server) stackTrace() (any, error) {
+	st, err := s.pausedState()
+	if err != nil {
+		return nil, err
+	}
+
+	frames := make([]StackFrame, len(st.frames))
+	for i, frame := range st.frames {
+		frames[i] = StackFrame{ID: i + 1, Name: frame.Function, Line: frame.Line, Column: 1}
+		if frame.File != "" {
+			frames[i].Source = &Source{Name: filepath.Base(frame.File), Path: frame.File}
+		}
+	}
+	return StackTraceResponseBody{StackFrames: frames, TotalFrames: len(frames)}, nil
+}
+
+// frameEnv 返回 frameID 对应的环境，frameID 为零时使用最内层
+func (st *stopState) frameEnv(frameID int) (*object.Environment, error) {
+	if frameID == 0 {
+		frameID = 1
+	}
+	if frameID < 1 || frameID > len(st.envs) || st.envs[frameID-1] == nil {
+		return nil, fmt.Errorf("invalid frame id: %d", frameID)
+	}
+	return st.envs[frameID-1], nil
+}
+
+func (st *stopState) handle(v any) int {
+	st.handles = append(st.handles, v)
+	return len(st.handles)
+}
+
+// scopes 把环境链的每一层作为一个作用域，最外层是全局变量
+func (s *Server) scopes(frameID int) (any, error) {
+	st, err := s.pausedState()
+	if err != nil {
+		return nil, err
+	}
+	env, err := st.frameEnv(frameID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := ScopesResponseBody{Scopes: []Scope{}}
+	for level := 0; env != nil; env, level = env.Outer(), level+1 {
+		name := "Closure"
+		switch {
+		case env.Outer() == nil:
+			name = "Globals"
+		case level == 0:
+			name = "Locals"
+		}
+		result.Scopes = append(result.Scopes, Scope{Name: name, VariablesReference: st.handle(env)})
+	}
+	return result, nil
+}
+
+func (s *Server) variables(ref int) (any, error) {
+	st, err := s.pausedState()
+	if err != nil {
+		return nil, err
+	}
+	if ref < 1 || ref > len(st.handles) {
+		return nil, fmt.Errorf("invalid variables reference: %d", ref)
+	}
+
+	result := VariablesResponseBody{Variables: []Variable{}}
+	switch v := st.handles[ref-1].(type) {
+	case *object.Environment:
+		for _, name := range v.Names() {
+			val, _ := v.Get(name)
+			result.Variables = append(result.Variables, st.variable(name, val))
+		}
+	case *object.Array:
+		for i, elem := range v.Elements {
+			result.Variables = append(result.Variables, st.variable(fmt.Sprintf("[%d]", i), elem))
+		}
+	case *object.Hash:
+		result.Variables = st.hashVariables(v)
+	case *object.Module:
+		result.Variables = st.hashVariables(v.Exports)
+	}
+	return result, nil
+}
+
+// hashVariables 按插入顺序列出键值对
+func (st *stopState) hashVariables(hash *object.Hash) []Variable {
+	pairs := hash.Items()
+	vars := make([]Variable, len(pairs))
+	for i, pair := range pairs {
+		vars[i] = st.variable(pair.Key.Inspect(), pair.Value)
+	}
+	return vars
+}
+
+// variable 数组、Hash 和模块可以展开，其他值没有子节点
+func (st *stopState) variable(name string, obj object.Object) Variable {
+	v := Variable{Name: name, Value: describe(obj), Type: strings.ToLower(string(obj.Type()))}
+	switch obj := obj.(type) {
+	case *object.Array:
+		if len(obj.Elements) != 0 {
+			v.VariablesReference = st.handle(obj)
+		}
+	case *object.Hash:
+		if obj.Len() != 0 {
+			v.VariablesReference = st.handle(obj)
+		}
+	case *object.Module:
+		v.VariablesReference = st.handle(obj)
+	}
+	return v
+}
+
+func describe(obj object.Object) string {
+	if fn, ok := obj.(*object.Function); ok {
+		return fn.Signature()
+	}
+	return obj.Inspect()
+}
+
+// evaluate 在暂停的调用层中求值表达式
+func (s *Server) evaluate(args *EvaluateArguments) (any, error) {
+	st, err := s.pausedState()
+	if err != nil {
+		return nil, err
+	}
+	env, err := st.frameEnv(args.FrameID)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser.New(lexer.New(args.Expression))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, errors.New(strings.Join(p.Errors(), "; "))
+	}
+
+	result := s.eval.EvalInFrame(program, env)
+	if result == nil {
+		result = evaluator.NULL
+	}
+	if errObj, ok := result.(*object.Error); ok {
+		return nil, errors.New(errObj.Message)
+	}
+	v := st.variable("", result)
+	return EvaluateResponseBody{Result: v.Value, Type: v.Type, VariablesReference: v.VariablesReference}, nil
+}
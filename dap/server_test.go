@@ -0,0 +1,354 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"shanyl2400/go_compiler/internal/framing"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type message struct {
+	Seq        int             `json:"seq"`
+	Type       string          `json:"type"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Command    string          `json:"command"`
+	Message    string          `json:"message"`
+	Event      string          `json:"event"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// client 通过管道与服务器交互，逐条发送请求并等待回复和事件
+type client struct {
+	t   *testing.T
+	in  *io.PipeWriter
+	msg chan message
+	err chan error
+	seq int
+
+	// events 是已经收到但还没有被 event 取走的事件
+	events []message
+}
+
+func newClient(t *testing.T) *client {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c := &client{t: t, in: inW, msg: make(chan message, 100), err: make(chan error, 1)}
+
+	go func() {
+		c.err <- Serve(inR, outW)
+		outW.Close()
+	}()
+	go func() {
+		defer close(c.msg)
+		r := bufio.NewReader(outR)
+		for {
+			body, err := framing.Read(r)
+			if err != nil {
+				return
+			}
+			var msg message
+			if err := json.Unmarshal(body, &msg); err != nil {
+				panic(err)
+			}
+			c.msg <- msg
+		}
+	}()
+	return c
+}
+
+func (c *client) next() message {
+	c.t.Helper()
+	select {
+	case msg, ok := <-c.msg:
+		require.True(c.t, ok, "connection closed")
+		return msg
+	case <-time.After(5 * time.Second):
+		c.t.Fatal("timeout waiting for message")
+		return message{}
+	}
+}
+
+// request 发送请求并返回回复，期间收到的事件留给 event
+func (c *client) request(command string, args any, body any) message {
+	c.t.Helper()
+	c.seq++
+	require.NoError(c.t, framing.Write(c.in, map[string]any{
+		"seq": c.seq, "type": "request", "command": command, "arguments": args,
+	}))
+
+	for {
+		msg := c.next()
+		if msg.Type == "event" {
+			c.events = append(c.events, msg)
+			continue
+		}
+		require.Equal(c.t, c.seq, msg.RequestSeq)
+		require.Equal(c.t, command, msg.Command)
+		if body != nil && msg.Success {
+			require.NoError(c.t, json.Unmarshal(msg.Body, body))
+		}
+		return msg
+	}
+}
+
+// event 等待名为 name 的事件，跳过之前的其他事件
+func (c *client) event(name string, body any) {
+	c.t.Helper()
+	for {
+		var msg message
+		if len(c.events) != 0 {
+			msg, c.events = c.events[0], c.events[1:]
+		} else {
+			msg = c.next()
+		}
+		require.Equal(c.t, "event", msg.Type)
+		if msg.Event != name {
+			continue
+		}
+		if body != nil {
+			require.NoError(c.t, json.Unmarshal(msg.Body, body))
+		}
+		return
+	}
+}
+
+func (c *client) stopped(reason string) {
+	c.t.Helper()
+	var body StoppedEventBody
+	c.event("stopped", &body)
+	assert.Equal(c.t, reason, body.Reason)
+}
+
+func (c *client) close() {
+	c.t.Helper()
+	require.True(c.t, c.request("disconnect", nil, nil).Success)
+	c.in.Close()
+	require.NoError(c.t, <-c.err)
+}
+
+func (c *client) launch(src string, args map[string]any) string {
+	c.t.Helper()
+	path := filepath.Join(c.t.TempDir(), "main.mk")
+	require.NoError(c.t, os.WriteFile(path, []byte(src), 0o644))
+
+	var caps Capabilities
+	require.True(c.t, c.request("initialize", map[string]any{"adapterID": "monkey"}, &caps).Success)
+	assert.True(c.t, caps.SupportsConfigurationDoneRequest)
+	c.event("initialized", nil)
+
+	if args == nil {
+		args = map[string]any{}
+	}
+	args["program"] = path
+	resp := c.request("launch", args, nil)
+	require.True(c.t, resp.Success, resp.Message)
+	return path
+}
+
+func (c *client) top() StackFrame {
+	c.t.Helper()
+	var trace StackTraceResponseBody
+	require.True(c.t, c.request("stackTrace", map[string]any{"threadId": threadID}, &trace).Success)
+	require.NotEmpty(c.t, trace.StackFrames)
+	return trace.StackFrames[0]
+}
+
+func (c *client) variables(ref int) map[string]Variable {
+	c.t.Helper()
+	var body VariablesResponseBody
+	require.True(c.t, c.request("variables", map[string]any{"variablesReference": ref}, &body).Success)
+	vars := make(map[string]Variable)
+	for _, v := range body.Variables {
+		vars[v.Name] = v
+	}
+	return vars
+}
+
+const source = `let add = fn(a, b) {
+    let sum = a + b;
+    sum
+};
+let x = add(1, 2);
+puts(x);
+let y = [x, {"k": x}];
+puts(len(y));
+`
+
+func TestSession(t *testing.T) {
+	c := newClient(t)
+	path := c.launch(source, nil)
+
+	var bps SetBreakpointsResponseBody
+	require.True(t, c.request("setBreakpoints", map[string]any{
+		"source":      map[string]any{"path": path},
+		"breakpoints": []map[string]any{{"line": 2}},
+	}, &bps).Success)
+	assert.Equal(t, []Breakpoint{{Verified: true, Line: 2}}, bps.Breakpoints)
+
+	require.True(t, c.request("configurationDone", nil, nil).Success)
+	c.stopped("breakpoint")
+
+	var threads ThreadsResponseBody
+	require.True(t, c.request("threads", nil, &threads).Success)
+	assert.Equal(t, []Thread{{ID: threadID, Name: "main"}}, threads.Threads)
+
+	// 调用栈
+	var trace StackTraceResponseBody
+	require.True(t, c.request("stackTrace", map[string]any{"threadId": threadID}, &trace).Success)
+	source := &Source{Name: "main.mk", Path: path}
+	assert.Equal(t, []StackFrame{
+		{ID: 1, Name: "add", Source: source, Line: 2, Column: 1},
+		{ID: 2, Name: "<main>", Source: source, Line: 5, Column: 1},
+	}, trace.StackFrames)
+
+	// 作用域和变量
+	var scopes ScopesResponseBody
+	require.True(t, c.request("scopes", map[string]any{"frameId": 1}, &scopes).Success)
+	require.Len(t, scopes.Scopes, 2)
+	assert.Equal(t, "Locals", scopes.Scopes[0].Name)
+	assert.Equal(t, "Globals", scopes.Scopes[1].Name)
+
+	locals := c.variables(scopes.Scopes[0].VariablesReference)
+	assert.Equal(t, Variable{Name: "a", Value: "1", Type: "integer"}, locals["a"])
+	assert.Equal(t, Variable{Name: "b", Value: "2", Type: "integer"}, locals["b"])
+	globals := c.variables(scopes.Scopes[1].VariablesReference)
+	assert.Equal(t, "fn(a, b)", globals["add"].Value)
+
+	var result EvaluateResponseBody
+	require.True(t, c.request("evaluate", map[string]any{"expression": "a * 10", "frameId": 1}, &result).Success)
+	assert.Equal(t, "10", result.Result)
+	resp := c.request("evaluate", map[string]any{"expression": "sum", "frameId": 1}, nil)
+	assert.False(t, resp.Success)
+	assert.Contains(t, resp.Message, "identifier not found: sum")
+
+	// 单步
+	require.True(t, c.request("next", map[string]any{"threadId": threadID}, nil).Success)
+	c.stopped("step")
+	assert.Equal(t, 3, c.top().Line)
+
+	require.True(t, c.request("stepOut", map[string]any{"threadId": threadID}, nil).Success)
+	c.stopped("step")
+	assert.Equal(t, "<main>", c.top().Name)
+	assert.Equal(t, 6, c.top().Line)
+
+	var out OutputEventBody
+	require.True(t, c.request("next", map[string]any{"threadId": threadID}, nil).Success)
+	c.event("output", &out)
+	assert.Equal(t, OutputEventBody{Category: "stdout", Output: "3\n"}, out)
+	c.stopped("step")
+	require.True(t, c.request("stepIn", map[string]any{"threadId": threadID}, nil).Success)
+	c.stopped("step")
+	assert.Equal(t, 8, c.top().Line)
+
+	// 展开数组和 Hash
+	require.True(t, c.request("scopes", map[string]any{"frameId": 1}, &scopes).Success)
+	require.Len(t, scopes.Scopes, 1)
+	y := c.variables(scopes.Scopes[0].VariablesReference)["y"]
+	assert.Equal(t, "array", y.Type)
+	elems := c.variables(y.VariablesReference)
+	assert.Equal(t, "3", elems["[0]"].Value)
+	assert.Equal(t, "3", c.variables(elems["[1]"].VariablesReference)["k"].Value)
+
+	var cont ContinueResponseBody
+	require.True(t, c.request("continue", map[string]any{"threadId": threadID}, &cont).Success)
+	assert.True(t, cont.AllThreadsContinued)
+	c.event("output", &out)
+	assert.Equal(t, "2\n", out.Output)
+
+	var exited ExitedEventBody
+	c.event("exited", &exited)
+	assert.Equal(t, 0, exited.ExitCode)
+	c.event("terminated", nil)
+
+	resp = c.request("stackTrace", map[string]any{"threadId": threadID}, nil)
+	assert.False(t, resp.Success)
+	c.close()
+}
+
+func TestStopOnEntryAndDisconnect(t *testing.T) {
+	c := newClient(t)
+	c.launch(source, map[string]any{"stopOnEntry": true})
+	require.True(t, c.request("configurationDone", nil, nil).Success)
+	c.stopped("entry")
+	assert.Equal(t, 1, c.top().Line)
+
+	// 暂停时断开连接会中止程序
+	c.close()
+}
+
+func TestPause(t *testing.T) {
+	c := newClient(t)
+	c.launch("let i = 0;\nwhile (true) {\n    let i = i + 1;\n}\n", nil)
+	require.True(t, c.request("configurationDone", nil, nil).Success)
+	require.True(t, c.request("pause", map[string]any{"threadId": threadID}, nil).Success)
+	c.stopped("pause")
+	// 暂停的位置取决于收到请求的时机
+	assert.Contains(t, []int{1, 2, 3}, c.top().Line)
+	c.close()
+}
+
+func TestRuntimeError(t *testing.T) {
+	c := newClient(t)
+	c.launch("let f = fn() { 1 + true };\nf();\n", nil)
+	require.True(t, c.request("configurationDone", nil, nil).Success)
+
+	var out OutputEventBody
+	c.event("output", &out)
+	assert.Equal(t, "stderr", out.Category)
+	assert.Contains(t, out.Output, "type mismatch: INTEGER + BOOLEAN")
+	assert.Contains(t, out.Output, "at f (")
+
+	var exited ExitedEventBody
+	c.event("exited", &exited)
+	assert.Equal(t, 1, exited.ExitCode)
+	c.close()
+}
+
+func TestLaunchErrors(t *testing.T) {
+	c := newClient(t)
+	require.True(t, c.request("initialize", nil, nil).Success)
+
+	resp := c.request("launch", map[string]any{"program": filepath.Join(t.TempDir(), "missing.mk")}, nil)
+	assert.False(t, resp.Success)
+
+	path := filepath.Join(t.TempDir(), "bad.mk")
+	require.NoError(t, os.WriteFile(path, []byte("let = 1;"), 0o644))
+	resp = c.request("launch", map[string]any{"program": path}, nil)
+	assert.False(t, resp.Success)
+	assert.Contains(t, resp.Message, "parser errors")
+
+	resp = c.request("continue", map[string]any{"threadId": threadID}, nil)
+	assert.False(t, resp.Success)
+	c.close()
+}
+
+func TestInvalidContentLength(t *testing.T) {
+	err := Serve(strings.NewReader("Content-Length: -1\r\n\r\n"), io.Discard)
+	assert.EqualError(t, err, `invalid Content-Length: "-1"`)
+}
+
+func TestMalformedRequest(t *testing.T) {
+	c := newClient(t)
+	body := "{not json"
+	_, err := fmt.Fprintf(c.in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	require.NoError(t, err)
+
+	resp := c.next()
+	assert.Equal(t, "response", resp.Type)
+	assert.False(t, resp.Success)
+	assert.Contains(t, resp.Message, "invalid character")
+
+	// 会话没有结束，后面的请求照常处理
+	require.True(t, c.request("initialize", nil, nil).Success)
+	c.close()
+}
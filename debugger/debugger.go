@@ -0,0 +1,247 @@
+// Package debugger 实现基于 evaluator.DebugHook 的命令行调试器
+package debugger
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"shanyl2400/go_compiler/ast"
+	"shanyl2400/go_compiler/evaluator"
+	"shanyl2400/go_compiler/lexer"
+	"shanyl2400/go_compiler/object"
+	"shanyl2400/go_compiler/parser"
+	"strconv"
+	"strings"
+)
+
+// ErrQuit 用户退出调试时中止执行
+var ErrQuit = errors.New("debugger quit")
+
+const prompt = "(debug) "
+
+const help = `commands:
+  break N, b N      set a breakpoint at line N
+  clear N           remove the breakpoint at line N
+  continue, c       run until the next breakpoint
+  step, s           step into the next statement
+  next, n           step over function calls
+  out, o            run until the current function returns
+  print EXPR, p     evaluate EXPR in the current frame
+  vars, v           print variables in the environment chain
+  where, bt         print the call stack
+  list, l           show source around the current line
+  quit, q           stop the program
+`
+
+type mode int
+
+const (
+	modeContinue mode = iota
+	modeStepIn
+	modeStepOver
+	modeStepOut
+)
+
+// Debugger 在断点或单步时暂停执行，从 in 读取命令
+type Debugger struct {
+	eval *evaluator.Evaluator
+	in   *bufio.Scanner
+	out  io.Writer
+
+	// lines 是被调试文件的源码，用于显示当前行
+	lines       []string
+	breakpoints map[int]bool
+
+	mode mode
+	// depth 是发出 next 或 out 命令时的调用深度
+	depth int
+}
+
+// New 创建调试器并把它设置为 e 的调试钩子，开始执行后在第一条语句暂停
+func New(e *evaluator.Evaluator, src string, in io.Reader, out io.Writer) *Debugger {
+	d := &Debugger{
+		eval:        e,
+		in:          bufio.NewScanner(in),
+		out:         out,
+		lines:       strings.Split(src, "\n"),
+		breakpoints: make(map[int]bool),
+		mode:        modeStepIn,
+	}
+	e.SetDebugHook(d.hook)
+	return d
+}
+
+// SetBreakpoint 在 line 行设置断点
+func (d *Debugger) SetBreakpoint(line int) {
+	d.breakpoints[line] = true
+}
+
+// Continue 开始执行时不在第一条语句暂停
+func (d *Debugger) Continue() {
+	d.mode = modeContinue
+}
+
+func (d *Debugger) hook(stmt ast.Statement, env *object.Environment) error {
+	line := ast.TokenOf(stmt).Line
+	depth := len(d.eval.Stack())
+
+	var stop bool
+	switch d.mode {
+	case modeStepIn:
+		stop = true
+	case modeStepOver:
+		stop = depth <= d.depth
+	case modeStepOut:
+		stop = depth < d.depth
+	}
+	// 断点只对被调试的文件有效，不包括导入的模块
+	if d.breakpoints[line] && d.inMainFile() {
+		stop = true
+	}
+	if !stop {
+		return nil
+	}
+
+	d.mode = modeContinue
+	d.printLocation(line)
+	return d.repl(env, line, depth)
+}
+
+// repl 读取并执行命令，直到继续执行
+func (d *Debugger) repl(env *object.Environment, line, depth int) error {
+	for {
+		fmt.Fprint(d.out, prompt)
+		if !d.in.Scan() {
+			// 输入结束时清除断点，运行到结束
+			d.breakpoints = make(map[int]bool)
+			fmt.Fprintln(d.out)
+			return nil
+		}
+
+		cmd, arg, _ := strings.Cut(strings.TrimSpace(d.in.Text()), " ")
+		arg = strings.TrimSpace(arg)
+		switch cmd {
+		case "":
+		case "break", "b":
+			if n, ok := d.lineArg(arg); ok {
+				d.breakpoints[n] = true
+				fmt.Fprintf(d.out, "breakpoint set at line %d\n", n)
+			}
+		case "clear":
+			if n, ok := d.lineArg(arg); ok {
+				delete(d.breakpoints, n)
+				fmt.Fprintf(d.out, "breakpoint cleared at line %d\n", n)
+			}
+		case "continue", "c":
+			d.mode = modeContinue
+			return nil
+		case "step", "s":
+			d.mode = modeStepIn
+			return nil
+		case "next", "n":
+			d.mode, d.depth = modeStepOver, depth
+			return nil
+		case "out", "o":
+			d.mode, d.depth = modeStepOut, depth
+			return nil
+		case "print", "p":
+			d.print(arg, env)
+		case "vars", "v":
+			d.printVars(env)
+		case "where", "bt":
+			for _, frame := range d.eval.Stack() {
+				fmt.Fprintf(d.out, "    at %s\n", frame)
+			}
+		case "list", "l":
+			d.list(line)
+		case "quit", "q":
+			return ErrQuit
+		case "help", "h":
+			fmt.Fprint(d.out, help)
+		default:
+			fmt.Fprintf(d.out, "unknown command: %s (type help for a list)\n", cmd)
+		}
+	}
+}
+
+func (d *Debugger) lineArg(arg string) (int, bool) {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 {
+		fmt.Fprintf(d.out, "invalid line number: %q\n", arg)
+		return 0, false
+	}
+	return n, true
+}
+
+func (d *Debugger) print(src string, env *object.Environment) {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		for _, msg := range p.Errors() {
+			fmt.Fprintf(d.out, "\t%s\n", msg)
+		}
+		return
+	}
+
+	result := d.eval.EvalInFrame(program, env)
+	if result == nil {
+		result = evaluator.NULL
+	}
+	fmt.Fprintln(d.out, result.Inspect())
+}
+
+// printVars 从当前环境开始逐层打印变量，不打印函数的定义
+func (d *Debugger) printVars(env *object.Environment) {
+	for level := 0; env != nil; level, env = level+1, env.Outer() {
+		names := env.Names()
+		if env.Outer() == nil {
+			fmt.Fprintln(d.out, "global:")
+		} else {
+			fmt.Fprintf(d.out, "scope %d:\n", level)
+		}
+		for _, name := range names {
+			val, _ := env.Get(name)
+			fmt.Fprintf(d.out, "    %s = %s\n", name, describe(val))
+		}
+	}
+}
+
+func describe(obj object.Object) string {
+	if fn, ok := obj.(*object.Function); ok {
+		return fn.Signature()
+	}
+	return obj.Inspect()
+}
+
+func (d *Debugger) printLocation(line int) {
+	frame := d.eval.Stack()[0]
+	file := frame.File
+	if file == "" {
+		file = "<input>"
+	}
+	fmt.Fprintf(d.out, "stopped in %s at %s:%d\n", frame.Function, file, line)
+	if d.inMainFile() && line <= len(d.lines) {
+		fmt.Fprintf(d.out, "%4d | %s\n", line, d.lines[line-1])
+	}
+}
+
+// inMainFile 判断当前执行的语句是否在被调试的文件中
+func (d *Debugger) inMainFile() bool {
+	stack := d.eval.Stack()
+	return stack[0].File == stack[len(stack)-1].File
+}
+
+// list 显示当前行前后的源码，当前行用 > 标出
+func (d *Debugger) list(line int) {
+	for n := line - 3; n <= line+3; n++ {
+		if n < 1 || n > len(d.lines) {
+			continue
+		}
+		marker := " "
+		if n == line {
+			marker = ">"
+		}
+		fmt.Fprintf(d.out, "%s%4d | %s\n", marker, n, d.lines[n-1])
+	}
+}
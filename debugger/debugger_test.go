@@ -0,0 +1,104 @@
+package debugger
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"shanyl2400/go_compiler/evaluator"
+	"shanyl2400/go_compiler/lexer"
+	"shanyl2400/go_compiler/object"
+	"shanyl2400/go_compiler/parser"
+	"strings"
+	"testing"
+)
+
+const source = `let add = fn(a, b) {
+    let sum = a + b;
+    sum
+};
+let x = add(1, 2);
+let y = x * 2;
+puts(y);`
+
+func run(t *testing.T, commands string) (string, object.Object) {
+	t.Helper()
+
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	var out bytes.Buffer
+	e := evaluator.New(object.NewContext(io.Discard, io.Discard))
+	New(e, source, strings.NewReader(commands), &out)
+	result := e.Eval(program, object.NewEnvironment())
+	return out.String(), result
+}
+
+func TestDebugger(t *testing.T) {
+	tests := []struct {
+		commands string
+		expected []string
+	}{
+		{
+			"q\n",
+			[]string{"stopped in <main> at <input>:1\n   1 | let add = fn(a, b) {\n"},
+		},
+		{
+			"b 2\nc\np a * 10\nbt\nq\n",
+			[]string{
+				"breakpoint set at line 2",
+				"stopped in add at <input>:2\n   2 |     let sum = a + b;\n",
+				"(debug) 10\n",
+				"    at add (<input>:2)\n    at <main> (<input>:5)\n",
+			},
+		},
+		{
+			"n\nn\nn\np x\nq\n",
+			[]string{"stopped in <main> at <input>:5", "stopped in <main> at <input>:6", "(debug) 3\n"},
+		},
+		{
+			"n\ns\ns\nv\no\nq\n",
+			[]string{
+				"stopped in add at <input>:2",
+				"stopped in add at <input>:3",
+				"    sum = 3\n",
+				"    a = 1\n",
+				"global:\n    add = fn(a, b)\n",
+				"stopped in <main> at <input>:6",
+			},
+		},
+		{
+			"p z\nfoo\nb x\nq\n",
+			[]string{
+				"identifier not found: z",
+				"unknown command: foo",
+				`invalid line number: "x"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		out, result := run(t, tt.commands)
+		errObj, ok := result.(*object.Error)
+		if !ok || !errors.Is(errObj.Err, ErrQuit) {
+			t.Errorf("commands %q: expected ErrQuit, got=%v", tt.commands, result)
+		}
+		for _, want := range tt.expected {
+			if !strings.Contains(out, want) {
+				t.Errorf("commands %q: output missing %q\n%s", tt.commands, want, out)
+			}
+		}
+	}
+}
+
+func TestDebuggerEndOfInput(t *testing.T) {
+	out, result := run(t, "b 6\n")
+	if _, ok := result.(*object.Error); ok {
+		t.Fatalf("unexpected error: %v", result.Inspect())
+	}
+	if strings.Contains(out, "at <input>:6") {
+		t.Errorf("breakpoints should be cleared at end of input\n%s", out)
+	}
+}
@@ -3,6 +3,8 @@ package evaluator
 import (
 	"fmt"
 	"shanyl2400/go_compiler/object"
+	"sort"
+	"strings"
 )
 
 var (
@@ -25,10 +27,69 @@ var (
 		"puts": {
 			Fn: puts,
 		},
+		"keys": {
+			Fn: keys,
+		},
+		"values": {
+			Fn: values,
+		},
+		"items": {
+			Fn: items,
+		},
+		"has": {
+			Fn: has,
+		},
+		"delete": {
+			Fn: deleteKey,
+		},
+		"merge": {
+			Fn: merge,
+		},
+		"contains": {
+			Fn: contains,
+		},
+	}
+
+	// builtinArity 是 builtins 中每个函数检查的参数个数，供 lint 等静态检查使用
+	builtinArity = map[string]Arity{
+		"len":      {1, 1},
+		"first":    {1, 1},
+		"last":     {1, 1},
+		"rest":     {1, 1},
+		"push":     {2, 2},
+		"puts":     {0, -1},
+		"keys":     {1, 1},
+		"values":   {1, 1},
+		"items":    {1, 1},
+		"has":      {2, 2},
+		"delete":   {2, 2},
+		"merge":    {1, -1},
+		"contains": {2, 2},
 	}
 )
 
-func first(args ...object.Object) object.Object {
+// Arity 是参数个数的范围，Max 为 -1 表示不限
+type Arity struct {
+	Min, Max int
+}
+
+// BuiltinArity 返回默认内置函数接受的参数个数，宿主注册的函数不包括在内
+func BuiltinArity(name string) (Arity, bool) {
+	arity, ok := builtinArity[name]
+	return arity, ok
+}
+
+// BuiltinNames 返回默认内置函数的名字，按字母排序
+func BuiltinNames() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func first(ctx *object.Context, args ...object.Object) object.Object {
 	if len(args) != 1 {
 		return newError("wrong number of arguments. got=%d, want=1", len(args))
 	}
@@ -43,7 +104,7 @@ func first(args ...object.Object) object.Object {
 	return NULL
 }
 
-func last(args ...object.Object) object.Object {
+func last(ctx *object.Context, args ...object.Object) object.Object {
 	if len(args) != 1 {
 		return newError("wrong number of arguments. got=%d, want=1", len(args))
 	}
@@ -59,7 +120,7 @@ func last(args ...object.Object) object.Object {
 	return NULL
 }
 
-func rest(args ...object.Object) object.Object {
+func rest(ctx *object.Context, args ...object.Object) object.Object {
 	if len(args) != 1 {
 		return newError("wrong number of arguments. got=%d, want=1", len(args))
 	}
@@ -72,19 +133,19 @@ func rest(args ...object.Object) object.Object {
 	if length > 0 {
 		newElements := make([]object.Object, length-1)
 		copy(newElements, arr.Elements[1:length])
-		return &object.Array{Elements: newElements}
+		return track(ctx, &object.Array{Elements: newElements})
 	}
 	return NULL
 }
 
-func puts(args ...object.Object) object.Object {
+func puts(ctx *object.Context, args ...object.Object) object.Object {
 	for _, arg := range args {
-		fmt.Println(arg.Inspect())
+		fmt.Fprintln(ctx.Stdout, arg.Inspect())
 	}
-	return nil
+	return NULL
 }
 
-func getLen(args ...object.Object) object.Object {
+func getLen(ctx *object.Context, args ...object.Object) object.Object {
 	if len(args) != 1 {
 		return newError("wrong number of arguments. got=%d, want=1", len(args))
 	}
@@ -93,11 +154,13 @@ func getLen(args ...object.Object) object.Object {
 		return &object.Integer{Value: int64(len(arg.Value))}
 	case *object.Array:
 		return &object.Integer{Value: int64(len(arg.Elements))}
+	case *object.Hash:
+		return &object.Integer{Value: int64(arg.Len())}
 	}
 	return newError("argument to `len` not supported, got %s", args[0].Type())
 }
 
-func push(args ...object.Object) object.Object {
+func push(ctx *object.Context, args ...object.Object) object.Object {
 	if len(args) != 2 {
 		return newError("wrong number of arguments. got=%d, want=2",
 			len(args))
@@ -114,5 +177,142 @@ func push(args ...object.Object) object.Object {
 	copy(newElements, arr.Elements)
 	newElements[length] = args[1]
 
-	return &object.Array{Elements: newElements}
+	return track(ctx, &object.Array{Elements: newElements})
+}
+
+func keys(ctx *object.Context, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	if args[0].Type() != object.HASH_OBJ {
+		return newError("argument to `keys` must be HASH, got %s", args[0].Type())
+	}
+
+	pairs := args[0].(*object.Hash).Items()
+	elements := make([]object.Object, len(pairs))
+	for i, pair := range pairs {
+		elements[i] = pair.Key
+	}
+	return track(ctx, &object.Array{Elements: elements})
+}
+
+func values(ctx *object.Context, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	if args[0].Type() != object.HASH_OBJ {
+		return newError("argument to `values` must be HASH, got %s", args[0].Type())
+	}
+
+	pairs := args[0].(*object.Hash).Items()
+	elements := make([]object.Object, len(pairs))
+	for i, pair := range pairs {
+		elements[i] = pair.Value
+	}
+	return track(ctx, &object.Array{Elements: elements})
+}
+
+// items 返回 [key, value] 数组组成的数组
+func items(ctx *object.Context, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	if args[0].Type() != object.HASH_OBJ {
+		return newError("argument to `items` must be HASH, got %s", args[0].Type())
+	}
+
+	pairs := args[0].(*object.Hash).Items()
+	elements := make([]object.Object, len(pairs))
+	for i, pair := range pairs {
+		elements[i] = track(ctx, &object.Array{Elements: []object.Object{pair.Key, pair.Value}})
+		if isError(elements[i]) {
+			return elements[i]
+		}
+	}
+	return track(ctx, &object.Array{Elements: elements})
+}
+
+func has(ctx *object.Context, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	if args[0].Type() != object.HASH_OBJ {
+		return newError("argument to `has` must be HASH, got %s", args[0].Type())
+	}
+	key, ok := object.AsHashable(args[1])
+	if !ok {
+		return newError("unusable as hash key: %s", args[1].Type())
+	}
+
+	_, ok = args[0].(*object.Hash).Get(key)
+	return nativeBooleanObject(ok)
+}
+
+// deleteKey 与 push 一样不修改参数，返回删除 key 之后的新 Hash
+func deleteKey(ctx *object.Context, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	if args[0].Type() != object.HASH_OBJ {
+		return newError("argument to `delete` must be HASH, got %s", args[0].Type())
+	}
+	key, ok := object.AsHashable(args[1])
+	if !ok {
+		return newError("unusable as hash key: %s", args[1].Type())
+	}
+
+	hash := copyHash(args[0].(*object.Hash))
+	hash.Delete(key)
+	return track(ctx, hash)
+}
+
+// merge 合并多个 Hash，后面参数中的键覆盖前面的值
+func merge(ctx *object.Context, args ...object.Object) object.Object {
+	if len(args) < 1 {
+		return newError("wrong number of arguments. got=%d, want>=1", len(args))
+	}
+
+	hash := object.NewHash()
+	for _, arg := range args {
+		h, ok := arg.(*object.Hash)
+		if !ok {
+			return newError("argument to `merge` must be HASH, got %s", arg.Type())
+		}
+		for _, pair := range h.Items() {
+			hash.Set(pair.Key.(object.Hashable), pair.Value)
+		}
+	}
+	return track(ctx, hash)
+}
+
+// contains 判断数组中是否存在与 value 结构相等的元素，字符串则判断子串
+func contains(ctx *object.Context, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	switch arg := args[0].(type) {
+	case *object.Array:
+		for _, elem := range arg.Elements {
+			if object.Equals(elem, args[1]) {
+				return TRUE
+			}
+		}
+		return FALSE
+	case *object.String:
+		sub, ok := args[1].(*object.String)
+		if !ok {
+			return newError("second argument to `contains` must be STRING, got %s", args[1].Type())
+		}
+		return nativeBooleanObject(strings.Contains(arg.Value, sub.Value))
+	}
+	return newError("argument to `contains` must be ARRAY or STRING, got %s", args[0].Type())
+}
+
+func copyHash(h *object.Hash) *object.Hash {
+	hash := object.NewHash()
+	for _, pair := range h.Items() {
+		hash.Set(pair.Key.(object.Hashable), pair.Value)
+	}
+	return hash
 }
@@ -0,0 +1,54 @@
+package evaluator
+
+import (
+	"shanyl2400/go_compiler/ast"
+	"shanyl2400/go_compiler/object"
+)
+
+// DebugHook 在每条语句执行前调用，env 是语句所在的环境。
+// 返回错误时中止执行，Eval 返回的错误对象的 Err 就是该错误。
+type DebugHook func(node ast.Statement, env *object.Environment) error
+
+// SetDebugHook 设置调试钩子，hook 为 nil 时取消
+func (e *Evaluator) SetDebugHook(hook DebugHook) {
+	e.hook = hook
+}
+
+func (e *Evaluator) callHook(node ast.Node, env *object.Environment) *object.Error {
+	if e.hook == nil {
+		return nil
+	}
+	stmt, ok := node.(ast.Statement)
+	if !ok {
+		return nil
+	}
+	if _, ok := stmt.(*ast.BlockStatement); ok {
+		return nil
+	}
+
+	if err := e.hook(stmt, env); err != nil {
+		return e.halt(err)
+	}
+	return nil
+}
+
+// Stack 返回当前的调用栈，最内层在前，可以在调试钩子中调用
+func (e *Evaluator) Stack() []object.StackFrame {
+	stack := make([]object.StackFrame, len(e.run.frames))
+	for i, frame := range e.run.frames {
+		stack[len(stack)-1-i] = frame
+	}
+	return stack
+}
+
+// EvalInFrame 在调试钩子中求值 node，不重置执行状态，也不会再次触发钩子
+func (e *Evaluator) EvalInFrame(node ast.Node, env *object.Environment) object.Object {
+	hook, frame := e.hook, *e.currentFrame()
+	e.hook = nil
+	defer func() {
+		e.hook = hook
+		*e.currentFrame() = frame
+	}()
+
+	return e.eval(node, env)
+}
@@ -1,19 +1,71 @@
 package evaluator
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"shanyl2400/go_compiler/ast"
 	"shanyl2400/go_compiler/object"
 )
 
 var (
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
+	TRUE  = object.TRUE
+	FALSE = object.FALSE
 
-	NULL = &object.Null{}
+	NULL = object.NULL
 )
 
+// Evaluator 保存一次执行共享的状态
+type Evaluator struct {
+	ctx *object.Context
+
+	// builtins 在默认内置函数基础上加入宿主注册的函数，只对当前实例可见
+	builtins map[string]*object.Builtin
+
+	// searchPath 和 modules 见 module.go，模块缓存在多次执行之间保留
+	searchPath []string
+	modules    map[string]*object.Module
+	noImports  bool
+
+	// hook 是调试钩子，每条语句执行前调用
+	hook DebugHook
+
+	limits Limits
+	run    runState
+}
+
+func New(ctx *object.Context) *Evaluator {
+	e := &Evaluator{
+		ctx:      ctx,
+		builtins: make(map[string]*object.Builtin, len(builtins)),
+		modules:  make(map[string]*object.Module),
+	}
+	ctx.SetAllocator(e.alloc)
+	for name, builtin := range builtins {
+		e.builtins[name] = builtin
+	}
+	return e
+}
+
+// RegisterBuiltin 注册内置函数，同名时覆盖默认实现
+func (e *Evaluator) RegisterBuiltin(name string, fn object.BuiltinFunction) {
+	e.builtins[name] = &object.Builtin{Fn: fn}
+}
+
+// Eval 使用标准输出和标准错误求值
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	return New(object.NewContext(os.Stdout, os.Stderr)).Eval(node, env)
+}
+
+func (e *Evaluator) eval(node ast.Node, env *object.Environment) object.Object {
+	if err := e.step(); err != nil {
+		return err
+	}
+	e.trackLine(node)
+	if err := e.callHook(node, env); err != nil {
+		return err
+	}
+
 	switch node := node.(type) {
 	// value
 	case *ast.IntegerLiteral:
@@ -21,81 +73,106 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.Boolean:
 		return nativeBooleanObject(node.Value)
 	case *ast.StringLiteral:
-		return &object.String{Value: node.Value}
+		return e.track(&object.String{Value: node.Value})
+	case *ast.NullLiteral:
+		return NULL
 	case *ast.Identifier:
-		return evalIdentifier(node, env)
+		return e.evalIdentifier(node, env)
 	//array
 	case *ast.ArrayLiteral:
-		elements := evalExpressions(node.Elements, env)
+		elements := e.evalExpressions(node.Elements, env)
 		if len(elements) == 1 && isError(elements[0]) {
 			return elements[0]
 		}
-		return &object.Array{Elements: elements}
+		return e.track(&object.Array{Elements: elements})
 	case *ast.HashLiteral:
-		return evalHashLiteral(node, env)
+		return e.evalHashLiteral(node, env)
 	//index
 	case *ast.IndexExpression:
-		left := Eval(node.Left, env)
-		if isError(left) {
-			return left
-		}
-		index := Eval(node.Index, env)
-		if isError(index) {
-			return index
-		}
-		return evalIndexExpression(left, index)
+		return e.evalChain(node, env)
+	case *ast.MemberExpression:
+		return e.evalChain(node, env)
 
 	//if
 	case *ast.IfExpression:
-		return evalIfExpression(node, env)
+		return e.evalIfExpression(node, env)
 	//while
 	case *ast.WhileStatement:
-		return evalWhileExpression(node, env)
+		return e.evalWhileExpression(node, env)
 	// expression
 	case *ast.PrefixExpression:
-		right := Eval(node.Right, env)
+		right := e.eval(node.Right, env)
+		if isError(right) {
+			return right
+		}
 		return evalPrefixExpression(node.Operator, right)
 	case *ast.InfixExpression:
-		left := Eval(node.Left, env)
-		right := Eval(node.Right, env)
-		return evalInfixExpression(node.Operator, left, right)
+		if node.Operator == "??" {
+			return e.evalNullishExpression(node, env)
+		}
+		left := e.eval(node.Left, env)
+		if isError(left) {
+			return left
+		}
+		right := e.eval(node.Right, env)
+		if isError(right) {
+			return right
+		}
+		result := evalInfixExpression(node.Operator, left, right)
+		if result.Type() == object.STRING_OBJ {
+			return e.track(result)
+		}
+		return result
 	// Blocks
 	case *ast.Program:
-		return evalProgram(node, env)
+		return e.evalProgram(node, env)
 	case *ast.BlockStatement:
-		return evalBlockStatement(node, env)
+		return e.evalBlockStatement(node, env)
 	case *ast.ExpressionStatement:
-		return Eval(node.Expression, env)
+		return e.eval(node.Expression, env)
 	//Function
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Body: body, Env: env}
-	case *ast.CallExpression:
-		function := Eval(node.Function, env)
-		if isError(function) {
-			return function
-		}
-		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
-			return args[0]
+		return &object.Function{
+			Parameters: params,
+			Body:       body,
+			Env:        env,
+			Name:       node.Name,
+			File:       e.currentFrame().File,
 		}
-		//Return
-		return applyFunction(function, args)
+	case *ast.CallExpression:
+		return e.evalChain(node, env)
 	case *ast.ReturnStatement:
-		return &object.ReturnValue{Value: Eval(node.Value, env)}
+		val := e.eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+		return &object.ReturnValue{Value: val}
+	case *ast.ThrowStatement:
+		return e.evalThrowStatement(node, env)
+	case *ast.TryExpression:
+		return e.evalTryExpression(node, env)
+	case *ast.ImportExpression:
+		return e.evalImportExpression(node)
+	case *ast.ExportStatement:
+		return e.evalExportStatement(node, env)
 		//Let
 	case *ast.LetStatement:
-		return evalLetStatement(node, env)
+		return e.evalLetStatement(node, env)
 	}
 	return nil
 }
 
-func evalProgram(program *ast.Program, env *object.Environment) object.Object {
+func (e *Evaluator) evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
 
+	if program.File != "" {
+		e.currentFrame().File = program.File
+	}
+
 	for _, stmt := range program.Statements {
-		result = Eval(stmt, env)
+		result = e.eval(stmt, env)
 		if result == nil {
 			continue
 		}
@@ -111,11 +188,11 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	return result
 }
 
-func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
+func (e *Evaluator) evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, stmt := range block.Statements {
-		result = Eval(stmt, env)
+		result = e.eval(stmt, env)
 		if result != nil {
 			rt := result.Type()
 			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
@@ -127,11 +204,11 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 	return result
 }
 
-func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
+func (e *Evaluator) evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
 	var result []object.Object
 
 	for _, exp := range exps {
-		evaluated := Eval(exp, env)
+		evaluated := e.eval(exp, env)
 		if isError(evaluated) {
 			return []object.Object{evaluated}
 		}
@@ -140,8 +217,8 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 	return result
 }
 
-func evalLetStatement(ls *ast.LetStatement, env *object.Environment) object.Object {
-	val := Eval(ls.Value, env)
+func (e *Evaluator) evalLetStatement(ls *ast.LetStatement, env *object.Environment) object.Object {
+	val := e.eval(ls.Value, env)
 	if isError(val) {
 		return val
 	}
@@ -149,52 +226,54 @@ func evalLetStatement(ls *ast.LetStatement, env *object.Environment) object.Obje
 	return val
 }
 
-func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
-	condition := Eval(ie.Condition, env)
+func (e *Evaluator) evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
+	condition := e.eval(ie.Condition, env)
 	if isTurthy(condition) {
-		return Eval(ie.Consequence, env)
+		return e.eval(ie.Consequence, env)
 	} else if ie.Alternative != nil {
-		return Eval(ie.Alternative, env)
+		return e.eval(ie.Alternative, env)
 	}
 	return NULL
 }
 
-func evalWhileExpression(we *ast.WhileStatement, env *object.Environment) object.Object {
-	condition := Eval(we.Condition, env)
+func (e *Evaluator) evalWhileExpression(we *ast.WhileStatement, env *object.Environment) object.Object {
+	condition := e.eval(we.Condition, env)
 	var out object.Object
 	for condition.Type() == object.BOOLEAN_OBJ && condition.Inspect() == "true" {
-		out = Eval(we.Consequence, env)
-		condition = Eval(we.Condition, env)
+		out = e.eval(we.Consequence, env)
+		if isError(out) || (out != nil && out.Type() == object.RETURN_VALUE_OBJ) {
+			return out
+		}
+		condition = e.eval(we.Condition, env)
+	}
+	if isError(condition) {
+		return condition
 	}
 	return out
 }
 
-func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
+func (e *Evaluator) evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+	hash := object.NewHash()
 
-	for keyNode, valueNode := range node.Pairs {
-		key := Eval(keyNode, env)
+	for _, keyNode := range node.Keys {
+		key := e.eval(keyNode, env)
 		if isError(key) {
 			return key
 		}
 
-		hashKey, ok := key.(object.Hashable)
+		hashKey, ok := object.AsHashable(key)
 		if !ok {
-			return newError("unhashable as high key: %s", key.Type())
+			return newError("unusable as hash key: %s", key.Type())
 		}
 
-		value := Eval(valueNode, env)
+		value := e.eval(node.Pairs[keyNode], env)
 		if isError(value) {
 			return value
 		}
 
-		pairs[hashKey.HashKey()] = object.HashPair{
-			Key:   key,
-			Value: value,
-		}
-
+		hash.Set(hashKey, value)
 	}
-	return &object.Hash{Pairs: pairs}
+	return e.track(hash)
 }
 
 func evalPrefixExpression(operator string, right object.Object) object.Object {
@@ -214,16 +293,29 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 		return evalIntegerInfixExpression(operator, left, right)
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
 		return evalStringInfixExpression(operator, left, right)
+	case operator == "==" && (left == NULL || right == NULL):
+		return nativeBooleanObject(left == right)
+	case operator == "!=" && (left == NULL || right == NULL):
+		return nativeBooleanObject(left != right)
 	case left.Type() != right.Type():
 		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
 	case operator == "==":
-		return nativeBooleanObject(left == right)
+		return nativeBooleanObject(object.Equals(left, right))
 	case operator == "!=":
-		return nativeBooleanObject(left != right)
+		return nativeBooleanObject(!object.Equals(left, right))
 	}
 	return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 }
 
+// evalNullishExpression 只有左侧为 null 时才会求值右侧
+func (e *Evaluator) evalNullishExpression(node *ast.InfixExpression, env *object.Environment) object.Object {
+	left := e.eval(node.Left, env)
+	if isError(left) || left != NULL {
+		return left
+	}
+	return e.eval(node.Right, env)
+}
+
 func evalBangOperatorExpression(right object.Object) object.Object {
 	switch right {
 	case TRUE:
@@ -263,6 +355,9 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	case "*":
 		return &object.Integer{Value: leftVal * rightVal}
 	case "/":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
 		return &object.Integer{Value: leftVal / rightVal}
 	case ">":
 		return nativeBooleanObject(leftVal > rightVal)
@@ -285,12 +380,12 @@ func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
 	return &object.Integer{Value: -value}
 }
 
-func evalIdentifier(i *ast.Identifier, env *object.Environment) object.Object {
+func (e *Evaluator) evalIdentifier(i *ast.Identifier, env *object.Environment) object.Object {
 	if val, ok := env.Get(i.Value); ok {
 		return val
 	}
 
-	if builtin, ok := builtins[i.Value]; ok {
+	if builtin, ok := e.builtins[i.Value]; ok {
 		return builtin
 	}
 
@@ -303,6 +398,8 @@ func evalIndexExpression(left, index object.Object) object.Object {
 		return evalArrayIndexExpression(left, index)
 	case left.Type() == object.HASH_OBJ:
 		return evalHashIndexExpression(left, index)
+	case left.Type() == object.MODULE_OBJ && index.Type() == object.STRING_OBJ:
+		return evalModuleMember(left.(*object.Module), index.(*object.String).Value)
 	}
 	return newError("index operator not supported: %s", left.Type())
 }
@@ -322,27 +419,134 @@ func evalArrayIndexExpression(left, index object.Object) object.Object {
 func evalHashIndexExpression(left, index object.Object) object.Object {
 	hash := left.(*object.Hash)
 
-	key, ok := index.(object.Hashable)
+	key, ok := object.AsHashable(index)
 	if !ok {
-		return newError("unhashable as hash key: %s", index.Type())
+		return newError("unusable as hash key: %s", index.Type())
 	}
 
-	pair, ok := hash.Pairs[key.HashKey()]
+	pair, ok := hash.Get(key)
 	if !ok {
 		return NULL
 	}
 	return pair.Value
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+// shortCircuit 表示可选链已经短路，只在一条链内部传递，链结束时变为 NULL
+var shortCircuit object.Object = &shortCircuitObject{}
+
+type shortCircuitObject struct {
+	object.Null
+	// object.Null 的大小为 0，指针可能与 NULL 相同，加一个字段保证 shortCircuit 唯一
+	_ byte
+}
+
+// evalChain 求值由成员访问、下标和调用组成的链，?. 或 ?[ 左边为 null 时整条链的结果为 null，
+// 例如 a?.b.c(1)[0] 在 a 为 null 时不再访问 .c、调用和下标
+func (e *Evaluator) evalChain(node ast.Expression, env *object.Environment) object.Object {
+	result := e.evalChainLink(node, env)
+	if result == shortCircuit {
+		return NULL
+	}
+	return result
+}
+
+// evalChainOperand 求值链中左边的部分，仍然是链的一环时不结束短路
+func (e *Evaluator) evalChainOperand(node ast.Expression, env *object.Environment) object.Object {
+	switch node.(type) {
+	case *ast.IndexExpression, *ast.MemberExpression, *ast.CallExpression:
+		if err := e.step(); err != nil {
+			return err
+		}
+		e.trackLine(node)
+		return e.evalChainLink(node, env)
+	}
+	return e.eval(node, env)
+}
+
+func (e *Evaluator) evalChainLink(node ast.Expression, env *object.Environment) object.Object {
+	switch node := node.(type) {
+	case *ast.IndexExpression:
+		left := e.evalChainOperand(node.Left, env)
+		if isError(left) || left == shortCircuit {
+			return left
+		}
+		if node.Optional && left == NULL {
+			return shortCircuit
+		}
+		index := e.eval(node.Index, env)
+		if isError(index) {
+			return index
+		}
+		return evalIndexExpression(left, index)
+	case *ast.MemberExpression:
+		obj := e.evalChainOperand(node.Object, env)
+		if isError(obj) || obj == shortCircuit {
+			return obj
+		}
+		if node.Optional && obj == NULL {
+			return shortCircuit
+		}
+		return e.evalMember(obj, node.Property.Value)
+	case *ast.CallExpression:
+		function := e.evalChainOperand(node.Function, env)
+		if isError(function) || function == shortCircuit {
+			return function
+		}
+		args := e.evalExpressions(node.Arguments, env)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
+		}
+		return e.applyFunction(function, args)
+	}
+	return e.eval(node, env)
+}
+
+func (e *Evaluator) evalMember(obj object.Object, name string) object.Object {
+	switch obj := obj.(type) {
+	case *object.Hash:
+		pair, ok := obj.Get(&object.String{Value: name})
+		if !ok {
+			return NULL
+		}
+		return pair.Value
+	case *object.HostObject:
+		return e.evalHostMember(obj, name)
+	case *object.Module:
+		return evalModuleMember(obj, name)
+	}
+	return newError("member access not supported: %s", obj.Type())
+}
+
+// Apply 调用函数对象，供宿主程序调用脚本中定义的函数
+func (e *Evaluator) Apply(fn object.Object, args ...object.Object) object.Object {
+	return e.ApplyContext(context.Background(), fn, args...)
+}
+
+// ApplyContext 与 Apply 相同，ctx 取消或超出执行限制时中止调用
+func (e *Evaluator) ApplyContext(ctx context.Context, fn object.Object, args ...object.Object) object.Object {
+	cancel := e.begin(ctx)
+	defer cancel()
+	return e.attachStack(e.applyFunction(fn, args))
+}
+
+func (e *Evaluator) applyFunction(fn object.Object, args []object.Object) object.Object {
 	switch function := fn.(type) {
 	case *object.Function:
+		if len(args) != len(function.Parameters) {
+			return newError("wrong number of arguments to %s. got=%d, want=%d",
+				functionName(function), len(args), len(function.Parameters))
+		}
+		if err := e.enterCall(function); err != nil {
+			return err
+		}
+		defer e.leaveCall()
+
 		extendedEnv := extendFunctionEnv(function, args)
-		evaluted := Eval(function.Body, extendedEnv)
+		evaluted := e.eval(function.Body, extendedEnv)
 
-		return unwrapReturnValue(evaluted)
+		return e.attachStack(unwrapReturnValue(evaluted))
 	case *object.Builtin:
-		return function.Fn(args...)
+		return function.Fn(e.ctx, args...)
 	}
 	return newError("not a function: %s", fn.Type())
 }
@@ -375,7 +579,7 @@ func newError(format string, a ...interface{}) *object.Error {
 }
 
 func isError(obj object.Object) bool {
-	return obj.Type() == object.ERROR_OBJ
+	return obj != nil && obj.Type() == object.ERROR_OBJ
 }
 
 func isTurthy(obj object.Object) bool {
@@ -3,10 +3,20 @@
 package evaluator
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"shanyl2400/go_compiler/ast"
 	"shanyl2400/go_compiler/lexer"
 	"shanyl2400/go_compiler/object"
 	"shanyl2400/go_compiler/parser"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestEvalIntegerExpression(t *testing.T) {
@@ -71,6 +81,33 @@ func TestEvalBooleanExpression(t *testing.T) {
 
 // evaluator/evaluator_test.go
 
+func TestStructuralEquality(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"[1, 2] == [1, 2]", true},
+		{"[1, 2] != [1, 2]", false},
+		{"[1, 2] == [2, 1]", false},
+		{"[1, [2, 3]] == [1, [2, 3]]", true},
+		{"[] == []", true},
+		{`{"a": 1, "b": [2]} == {"b": [2], "a": 1}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{`{"a": 1} != {"a": 1, "b": 2}`, true},
+		{"let f = fn(x) { x }; f == f", true},
+		{"fn(x) { x } == fn(x) { x }", false},
+		{`contains([1, [2, 3]], [2, 3])`, true},
+		{`contains([1, {"a": 1}], {"a": 1})`, true},
+		{`contains([1, 2], 3)`, false},
+		{`contains("hello", "ell")`, true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
 func TestIfElseExpressions(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -177,6 +214,18 @@ func TestErrorHandling(t *testing.T) {
 			"-true",
 			"unknown operator: -BOOLEAN",
 		},
+		{
+			"10 / (5 - 5)",
+			"division by zero",
+		},
+		{
+			"let add = fn(a, b) { a + b }; add(1)",
+			"wrong number of arguments to add. got=1, want=2",
+		},
+		{
+			"fn(a) { a }(1, 2)",
+			"wrong number of arguments to <anonymous>. got=2, want=1",
+		},
 		{
 			"true + false;",
 			"unknown operator: BOOLEAN + BOOLEAN",
@@ -213,6 +262,10 @@ if (10 > 1) {
 			`{"name": "Monkey"}[fn(x) { x }];`,
 			"unusable as hash key: FUNCTION",
 		},
+		{
+			`{"name": "Monkey"}[[1, fn(x) { x }]];`,
+			"unusable as hash key: ARRAY",
+		},
 	}
 
 	for _, tt := range tests {
@@ -339,6 +392,61 @@ func TestBuiltinFunctions(t *testing.T) {
 	}
 }
 
+func TestPutsWritesToContext(t *testing.T) {
+	var stdout bytes.Buffer
+	l := lexer.New(`let r = puts("hello", 1); [puts()]; r`)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	evaluated := New(object.NewContext(&stdout, &stdout)).Eval(program, object.NewEnvironment())
+
+	if stdout.String() != "hello\n1\n" {
+		t.Errorf("puts wrote wrong output. got=%q", stdout.String())
+	}
+	testNullObject(t, evaluated)
+}
+
+func TestHashBuiltinFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`len({"a": 1, "b": 2})`, "2"},
+		{`keys({"b": 1, "a": 2, 3: 3})`, "[b, a, 3]"},
+		{`values({"b": 1, "a": 2, 3: 3})`, "[1, 2, 3]"},
+		{`items({"b": 1, "a": 2})`, "[[b, 1], [a, 2]]"},
+		{`has({"a": 1}, "a")`, "true"},
+		{`has({"a": 1}, "b")`, "false"},
+		{`let h = {"a": 1, "b": 2}; delete(h, "a")`, "{b: 2}"},
+		{`let h = {"a": 1, "b": 2}; delete(h, "a"); h`, "{a: 1, b: 2}"},
+		{`merge({"a": 1, "b": 2}, {"c": 3, "a": 4})`, "{a: 4, b: 2, c: 3}"},
+		{`keys([1])`, "ERROR: argument to `keys` must be HASH, got ARRAY"},
+		{`has({}, fn(x) { x })`, "ERROR: unusable as hash key: FUNCTION"},
+		{`merge({}, 1)`, "ERROR: argument to `merge` must be HASH, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestHashInspectOrder(t *testing.T) {
+	input := `{"z": 1, "y": 2, "x": 3, 1: 4, true: 5}`
+	expected := "{z: 1, y: 2, x: 3, 1: 4, true: 5}"
+
+	for i := 0; i < 10; i++ {
+		evaluated := testEval(input)
+		if evaluated.Inspect() != expected {
+			t.Fatalf("hash order not deterministic. expected=%q, got=%q",
+				expected, evaluated.Inspect())
+		}
+	}
+}
+
 func TestArrayLiterals(t *testing.T) {
 	input := "[1, 2 * 2, 3 + 3]"
 
@@ -433,26 +541,30 @@ func TestHashLiterals(t *testing.T) {
 		t.Fatalf("Eval didn't return Hash. got=%T (%+v)", evaluated, evaluated)
 	}
 
-	expected := map[object.HashKey]int64{
-		(&object.String{Value: "one"}).HashKey():   1,
-		(&object.String{Value: "two"}).HashKey():   2,
-		(&object.String{Value: "three"}).HashKey(): 3,
-		(&object.Integer{Value: 4}).HashKey():      4,
-		TRUE.HashKey():                             5,
-		FALSE.HashKey():                            6,
+	expected := []struct {
+		key   object.Hashable
+		value int64
+	}{
+		{&object.String{Value: "one"}, 1},
+		{&object.String{Value: "two"}, 2},
+		{&object.String{Value: "three"}, 3},
+		{&object.Integer{Value: 4}, 4},
+		{TRUE, 5},
+		{FALSE, 6},
 	}
 
-	if len(result.Pairs) != len(expected) {
-		t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
+	if result.Len() != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", result.Len())
 	}
 
-	for expectedKey, expectedValue := range expected {
-		pair, ok := result.Pairs[expectedKey]
+	for _, tt := range expected {
+		pair, ok := result.Get(tt.key)
 		if !ok {
-			t.Errorf("no pair for given key in Pairs")
+			t.Errorf("no pair for given key %s in Pairs", tt.key.Inspect())
+			continue
 		}
 
-		testIntegerObject(t, pair.Value, expectedValue)
+		testIntegerObject(t, pair.Value, tt.value)
 	}
 }
 
@@ -491,6 +603,18 @@ func TestHashIndexExpressions(t *testing.T) {
 			`{false: 5}[false]`,
 			5,
 		},
+		{
+			`{[1, "a"]: 5}[[1, "a"]]`,
+			5,
+		},
+		{
+			`{[1, [2, 3]]: 5}[[1, [2, 3]]]`,
+			5,
+		},
+		{
+			`{[1, "a"]: 5}[["a", 1]]`,
+			nil,
+		},
 	}
 
 	for _, tt := range tests {
@@ -504,6 +628,439 @@ func TestHashIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestNullAndOptionalChaining(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"null", "null"},
+		{"null == null", "true"},
+		{"1 == null", "false"},
+		{"1 != null", "true"},
+		{"null ?? 5", "5"},
+		{"3 ?? 5", "3"},
+		{"false ?? 5", "false"},
+		{"null ?? foo", "ERROR: identifier not found: foo"},
+		{"1 ?? foo", "1"},
+		{`let c = {"db": {"host": "h"}}; c.db.host`, "h"},
+		{`let c = {"db": {"host": "h"}}; c.cache`, "null"},
+		{`let c = {}; c.db.host`, "ERROR: member access not supported: NULL"},
+		{`let c = {}; c.db?.host`, "null"},
+		{`let c = {}; c["db"]["host"]`, "ERROR: index operator not supported: NULL"},
+		{`let c = {}; c["db"]?["host"]`, "null"},
+		{`let c = {"ports": [80]}; c?.ports?[0]`, "80"},
+		{`let c = {}; c?.db?.port ?? 5432`, "5432"},
+		{"1.a", "ERROR: member access not supported: INTEGER"},
+		// 短路跳过链中剩下的成员访问、下标和调用
+		{"null?.x.y", "null"},
+		{"null?.f()", "null"},
+		{"let a = null; a?.f(1)", "null"},
+		{"null?.f(missing)[0].g", "null"},
+		{`let c = {}; c.db?.host.name["x"]`, "null"},
+		{`let c = {"a": {}}; c?.a.b.c`, "ERROR: member access not supported: NULL"},
+		{"[null?.x.y, 1]", "[null, 1]"},
+		{"null?.x.y ?? 1", "1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestTryCatch(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`throw "boom"`, "ERROR: boom"},
+		{`throw 1; 2`, "ERROR: 1"},
+		{`try { throw "boom" } catch (e) { e.message }`, "boom"},
+		{`try { throw [1, 2] } catch (e) { e.value }`, "[1, 2]"},
+		{`try { 1 } catch (e) { 2 }`, "1"},
+		{`try { foo } catch (e) { e.message }`, "identifier not found: foo"},
+		{`try { len(1) } catch (e) { e.message }`, "argument to `len` not supported, got INTEGER"},
+		{`try { {}.a.b } catch { "recovered" }`, "recovered"},
+		{`let x = try { throw "a" } catch (e) { 5 }; x * 2`, "10"},
+		{`try { throw "a" } catch (e) { throw e.message + "b" }`, "ERROR: ab"},
+		{`try { throw {"message": "m", "code": 1} } catch (e) { throw e.value }`, "ERROR: m"},
+		{`try { throw "a" } catch (e) { 1 }; e`, "ERROR: identifier not found: e"},
+		{`let f = fn() { throw "inner" }; try { f() } catch (e) { e.stack }`,
+			"[f (<input>:1), <main> (<input>:1)]"},
+		{`let f = fn(x) { if (x > 2) { throw x } f(x + 1) }; try { f(0) } catch (e) { e.value }`, "3"},
+		// finally 总是执行，出错或 return 时覆盖结果
+		{`let a = 0; try { let a = 1 } finally { let a = a + 1 }; a`, "2"},
+		{`let a = 0; try { throw "x" } catch { 1 } finally { let a = a + 1 }; a`, "1"},
+		{`try { throw "x" } finally { 1 }`, "ERROR: x"},
+		{`try { 1 } finally { throw "y" }`, "ERROR: y"},
+		{`let f = fn() { try { return 1 } finally { 2 } }; f()`, "1"},
+		{`let f = fn() { try { return 1 } finally { return 2 } }; f()`, "2"},
+		{`let f = fn() { try { throw "x" } catch (e) { return e.message } }; f()`, "x"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestLimitsNotCatchable(t *testing.T) {
+	input := `
+let f = fn(x) { f(x + 1) };
+let finished = false;
+try { f(0) } catch (e) { 1 } finally { finished = true };
+finished`
+
+	program := parser.New(lexer.New(input)).ParseProgram()
+	e := New(object.NewContext(io.Discard, io.Discard))
+	e.SetLimits(Limits{MaxCallDepth: 50})
+
+	evaluated := e.Eval(program, object.NewEnvironment())
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T (%+v)", evaluated, evaluated)
+	}
+	if !errors.Is(errObj.Err, ErrCallDepthExceeded) {
+		t.Errorf("wrong error. got=%q", errObj.Message)
+	}
+}
+
+func TestImport(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib")
+	files := map[string]string{
+		"lib/math.mk": `let helper = import "./helper";
+export let double = fn(x) { helper.twice(x) };
+export let name = "math";
+let private = 1;
+puts("math loaded");`,
+		"lib/helper.mk": `export let twice = fn(x) { x * 2 };`,
+		"lib/broken.mk": `export let f = fn() { 1 + true };`,
+		"lib/bad.mk":    `let = ;`,
+		"a.mk":          `let b = import "./b"; export let a = 1;`,
+		"b.mk":          `let a = import "./a"; export let b = 1;`,
+	}
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let m = import "math"; m.double(21)`, "42"},
+		{`let m = import "math.mk"; m["name"]`, "math"},
+		{`let m = import "math"; let n = import "math"; m == n`, "true"},
+		{`let m = import "math"; m.private`, "ERROR: private is not exported by module " + filepath.Join(lib, "math.mk")},
+		{`let m = import "math"; helper`, "ERROR: identifier not found: helper"},
+		{`import "missing"`, "ERROR: module not found: missing"},
+		{`let m = import "broken"; m.f()`, "ERROR: type mismatch: INTEGER + BOOLEAN"},
+		{`import "./a"`, "ERROR: import cycle: " + strings.Join([]string{
+			filepath.Join(dir, "a.mk"), filepath.Join(dir, "b.mk"), filepath.Join(dir, "a.mk")}, " -> ")},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		program.File = filepath.Join(dir, "main.mk")
+
+		var out bytes.Buffer
+		e := New(object.NewContext(&out, &out))
+		e.SetSearchPath(lib)
+
+		evaluated := e.Eval(program, object.NewEnvironment())
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+		if strings.Count(out.String(), "math loaded") > 1 {
+			t.Errorf("module evaluated more than once for %q", tt.input)
+		}
+	}
+
+	e := New(object.NewContext(io.Discard, io.Discard))
+	e.SetSearchPath(lib)
+	evaluated := e.Eval(parser.New(lexer.New(`import "bad"`)).ParseProgram(), object.NewEnvironment())
+	if !strings.Contains(evaluated.Inspect(), "parser errors") {
+		t.Errorf("expected parser errors. got=%q", evaluated.Inspect())
+	}
+}
+
+func TestImportStaysInSearchPath(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib")
+	secret := filepath.Join(dir, "secret.mk")
+	for path, src := range map[string]string{
+		filepath.Join(lib, "ok.mk"): `export let x = 1;`,
+		secret:                      `export let x = 2;`,
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(secret, filepath.Join(lib, "link.mk")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "ok".x`, "1"},
+		{`import "sub/../ok".x`, "1"},
+		{`import "` + secret + `"`, "ERROR: import path must be relative: " + secret},
+		{`import "../secret"`, "ERROR: module outside the search path: ../secret"},
+		{`import "ok/../../secret"`, "ERROR: module outside the search path: ok/../../secret"},
+		{`import "../missing"`, "ERROR: module outside the search path: ../missing"},
+		{`import "link"`, "ERROR: module outside the search path: link"},
+		// 没有顶层文件时 ./ 相对于当前目录，不在搜索路径内
+		{`import "./secret"`, "ERROR: module outside the search path: ./secret"},
+	}
+
+	for _, tt := range tests {
+		e := New(object.NewContext(io.Discard, io.Discard))
+		e.SetSearchPath(lib)
+		evaluated := e.Eval(parser.New(lexer.New(tt.input)).ParseProgram(), object.NewEnvironment())
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+
+	e := New(object.NewContext(io.Discard, io.Discard))
+	e.DisableImports()
+	evaluated := e.Eval(parser.New(lexer.New(`import "ok"`)).ParseProgram(), object.NewEnvironment())
+	if evaluated.Inspect() != "ERROR: import is disabled: ok" {
+		t.Errorf("expected import to be disabled. got=%q", evaluated.Inspect())
+	}
+}
+
+func TestExecutionLimits(t *testing.T) {
+	tests := []struct {
+		input  string
+		limits Limits
+		ctx    func() (context.Context, context.CancelFunc)
+		err    error
+	}{
+		{
+			input:  "while (true) { 1 }",
+			limits: Limits{MaxSteps: 1000},
+			err:    ErrStepLimitExceeded,
+		},
+		{
+			input:  "let f = fn(n) { f(n + 1) }; f(0)",
+			limits: Limits{MaxCallDepth: 50},
+			err:    ErrCallDepthExceeded,
+		},
+		{
+			input:  "while (true) { 1 }",
+			limits: Limits{Timeout: 10 * time.Millisecond},
+			err:    context.DeadlineExceeded,
+		},
+		{
+			input: "while (true) { 1 }",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 10*time.Millisecond)
+			},
+			err: context.DeadlineExceeded,
+		},
+		{
+			input: "let i = 0; while (i < 10) { let i = i + 1; }; i",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx, cancel
+			},
+			err: context.Canceled,
+		},
+		{
+			input:  "let f = fn(n) { if (n > 0) { f(n - 1) } else { n } }; f(10)",
+			limits: Limits{MaxSteps: 10000, MaxCallDepth: 20},
+			err:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		e := New(object.NewContext(io.Discard, io.Discard))
+		e.SetLimits(tt.limits)
+
+		ctx, cancel := context.Background(), context.CancelFunc(func() {})
+		if tt.ctx != nil {
+			ctx, cancel = tt.ctx()
+		}
+		evaluated := e.EvalContext(ctx, program, object.NewEnvironment())
+		cancel()
+
+		errObj, ok := evaluated.(*object.Error)
+		if tt.err == nil {
+			if ok {
+				t.Errorf("%q: unexpected error %q", tt.input, errObj.Message)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("%q: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if !errors.Is(errObj.Err, tt.err) {
+			t.Errorf("%q: wrong error. expected=%v, got=%v", tt.input, tt.err, errObj.Err)
+		}
+	}
+}
+
+func TestRecursionDepth(t *testing.T) {
+	tests := []struct {
+		input    string
+		maxDepth int
+		expected string
+	}{
+		{
+			"let countdown = fn(n) { countdown(n + 1) }; countdown(0)",
+			0,
+			"maximum recursion depth exceeded in countdown",
+		},
+		{
+			"let f = fn(g) { g(g) }; f(fn(h) { h(h) })",
+			100,
+			"maximum recursion depth exceeded in <anonymous>",
+		},
+		{
+			"let sum = fn(n) { if (n == 0) { 0 } else { n + sum(n - 1) } }; sum(50)",
+			10,
+			"maximum recursion depth exceeded in sum",
+		},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		e := New(object.NewContext(io.Discard, io.Discard))
+		e.SetLimits(Limits{MaxCallDepth: tt.maxDepth})
+
+		evaluated := e.Eval(program, object.NewEnvironment())
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("%q: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expected || !errors.Is(errObj.Err, ErrCallDepthExceeded) {
+			t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		}
+	}
+
+	testIntegerObject(t, testEval("let sum = fn(n) { if (n == 0) { 0 } else { n + sum(n - 1) } }; sum(500)"), 125250)
+}
+
+func TestMemoryLimit(t *testing.T) {
+	tests := []struct {
+		input string
+		limit int64
+		err   bool
+	}{
+		{"let a = [1]; while (true) { let a = push(a, a); }", 1 << 20, true},
+		{`let s = "x"; while (true) { let s = s + s; }`, 1 << 20, true},
+		{`let h = {}; let i = 0; while (true) { let h = merge(h, {i: i}); let i = i + 1; }`, 1 << 20, true},
+		{"let a = [1, 2, 3]; push(a, 4)", 1 << 20, false},
+		{"[1, 2, 3]", 32, true},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		e := New(object.NewContext(io.Discard, io.Discard))
+		e.SetLimits(Limits{MaxMemory: tt.limit})
+
+		evaluated := e.Eval(program, object.NewEnvironment())
+		errObj, ok := evaluated.(*object.Error)
+		if !tt.err {
+			if ok {
+				t.Errorf("%q: unexpected error %q", tt.input, errObj.Message)
+			}
+			continue
+		}
+		if !ok || !errors.Is(errObj.Err, ErrMemoryLimitExceeded) {
+			t.Errorf("%q: expected memory limit error. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if !strings.HasPrefix(errObj.Message, "memory limit exceeded: allocated ") {
+			t.Errorf("%q: unclear error message %q", tt.input, errObj.Message)
+		}
+	}
+}
+
+// TestSandbox 用同一组限制执行不可信的代码，每段代码都应该返回错误而不是使进程崩溃
+func TestSandbox(t *testing.T) {
+	limits := Limits{MaxSteps: 100000, MaxCallDepth: 100, Timeout: time.Second, MaxMemory: 1 << 20}
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let f = fn(a, b) { a + b }; f(1)", "wrong number of arguments to f. got=1, want=2"},
+		{"1 / 0", "division by zero"},
+		{"let n = 0; 10 / n", "division by zero"},
+		{"while (true) { 1 }", "step limit exceeded"},
+		{"let f = fn() { f() }; f()", "maximum recursion depth exceeded in f"},
+		{"let a = [1]; while (true) { let a = push(a, a); }", "memory limit exceeded"},
+		{"null?.x.y()", ""},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		e := New(object.NewContext(io.Discard, io.Discard))
+		e.SetLimits(limits)
+
+		evaluated := e.EvalContext(context.Background(), program, object.NewEnvironment())
+		errObj, ok := evaluated.(*object.Error)
+		if tt.expected == "" {
+			if ok {
+				t.Errorf("%q: unexpected error %q", tt.input, errObj.Message)
+			}
+			continue
+		}
+		if !ok || !strings.HasPrefix(errObj.Message, tt.expected) {
+			t.Errorf("%q: expected error %q. got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+
+	// 宿主调用脚本函数时参数个数不对
+	e := New(object.NewContext(io.Discard, io.Discard))
+	e.SetLimits(limits)
+	env := object.NewEnvironment()
+	e.EvalContext(context.Background(), parser.New(lexer.New("let f = fn(a, b) { a / b }")).ParseProgram(), env)
+	f, _ := env.Get("f")
+	evaluated := e.ApplyContext(context.Background(), f, &object.Integer{Value: 1})
+	if !isError(evaluated) {
+		t.Errorf("ApplyContext with too few arguments expected error. got=%s", evaluated.Inspect())
+	}
+	evaluated = e.ApplyContext(context.Background(), f, &object.Integer{Value: 1}, &object.Integer{Value: 0})
+	if !isError(evaluated) || evaluated.(*object.Error).Message != "division by zero" {
+		t.Errorf("ApplyContext dividing by zero expected error. got=%s", evaluated.Inspect())
+	}
+}
+
+func TestLimitsResetBetweenRuns(t *testing.T) {
+	e := New(object.NewContext(io.Discard, io.Discard))
+	e.SetLimits(Limits{MaxSteps: 100})
+	env := object.NewEnvironment()
+
+	program := parser.New(lexer.New("let a = 1 + 2; a")).ParseProgram()
+	for i := 0; i < 50; i++ {
+		testIntegerObject(t, e.Eval(program, env), 3)
+	}
+}
+
 func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
 	result, ok := obj.(*object.Boolean)
 	if !ok {
@@ -550,3 +1107,129 @@ func testNullObject(t *testing.T, obj object.Object) bool {
 	}
 	return true
 }
+
+func TestErrorStackTrace(t *testing.T) {
+	input := `let inner = fn(x) {
+  x + true
+};
+let outer = fn(y) {
+  let z = 1;
+  fn() { inner(y) }()
+};
+outer(1);`
+
+	program := parser.New(lexer.New(input)).ParseProgram()
+	program.File = "main.mk"
+
+	errObj, ok := Eval(program, object.NewEnvironment()).(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned")
+	}
+
+	expected := []object.StackFrame{
+		{Function: "inner", File: "main.mk", Line: 2},
+		{Function: "<anonymous>", File: "main.mk", Line: 6},
+		{Function: "outer", File: "main.mk", Line: 6},
+		{Function: "<main>", File: "main.mk", Line: 8},
+	}
+	if len(errObj.Stack) != len(expected) {
+		t.Fatalf("wrong stack depth. want=%d, got=%d\n%s",
+			len(expected), len(errObj.Stack), errObj.StackTrace())
+	}
+	for i, frame := range expected {
+		if errObj.Stack[i] != frame {
+			t.Errorf("frame[%d] wrong. want=%s, got=%s", i, frame, errObj.Stack[i])
+		}
+	}
+
+	trace := errObj.StackTrace()
+	if !strings.HasPrefix(trace, "    at inner (main.mk:2)\n") {
+		t.Errorf("wrong stack trace:\n%s", trace)
+	}
+}
+
+func TestDebugHook(t *testing.T) {
+	input := `let add = fn(a, b) {
+  a + b
+};
+let x = add(1, 2);
+x`
+
+	program := parser.New(lexer.New(input)).ParseProgram()
+	e := New(object.NewContext(io.Discard, io.Discard))
+
+	var lines []int
+	var depths []int
+	e.SetDebugHook(func(stmt ast.Statement, env *object.Environment) error {
+		switch stmt := stmt.(type) {
+		case *ast.LetStatement:
+			lines = append(lines, stmt.Token.Line)
+		case *ast.ExpressionStatement:
+			lines = append(lines, stmt.Token.Line)
+		}
+		depths = append(depths, len(e.Stack()))
+
+		if len(lines) == 3 {
+			// 在函数内部求值参数，不影响调用栈
+			a, _ := env.Get("a")
+			testIntegerObject(t, a, 1)
+			testIntegerObject(t, e.EvalInFrame(parser.New(lexer.New("a * 10")).ParseProgram(), env), 10)
+		}
+		return nil
+	})
+
+	testIntegerObject(t, e.Eval(program, object.NewEnvironment()), 3)
+	if !reflect.DeepEqual(lines, []int{1, 4, 2, 5}) {
+		t.Errorf("wrong statement lines. got=%v", lines)
+	}
+	if !reflect.DeepEqual(depths, []int{1, 1, 2, 1}) {
+		t.Errorf("wrong call depths. got=%v", depths)
+	}
+
+	stop := errors.New("stop")
+	e.SetDebugHook(func(stmt ast.Statement, env *object.Environment) error {
+		return stop
+	})
+	errObj, ok := e.Eval(program, object.NewEnvironment()).(*object.Error)
+	if !ok || !errors.Is(errObj.Err, stop) {
+		t.Errorf("hook error did not stop execution. got=%v", errObj)
+	}
+}
+
+// TestBuiltinArity 检查 BuiltinArity 与内置函数自身检查的参数个数一致
+func TestBuiltinArity(t *testing.T) {
+	ctx := object.NewContext(io.Discard, io.Discard)
+	call := func(name string, n int) string {
+		args := make([]object.Object, n)
+		for i := range args {
+			args[i] = NULL
+		}
+		if err, ok := builtins[name].Fn(ctx, args...).(*object.Error); ok {
+			return err.Message
+		}
+		return ""
+	}
+	isArityError := func(msg string) bool {
+		return strings.HasPrefix(msg, "wrong number of arguments")
+	}
+
+	for _, name := range BuiltinNames() {
+		arity, ok := BuiltinArity(name)
+		if !ok {
+			t.Errorf("%s: no arity", name)
+			continue
+		}
+		if arity.Min > 0 && !isArityError(call(name, arity.Min-1)) {
+			t.Errorf("%s: accepts %d arguments", name, arity.Min-1)
+		}
+		if isArityError(call(name, arity.Min)) {
+			t.Errorf("%s: rejects %d arguments", name, arity.Min)
+		}
+		if arity.Max != -1 && !isArityError(call(name, arity.Max+1)) {
+			t.Errorf("%s: accepts %d arguments", name, arity.Max+1)
+		}
+	}
+	if len(builtinArity) != len(builtins) {
+		t.Errorf("builtinArity has %d entries, builtins has %d", len(builtinArity), len(builtins))
+	}
+}
@@ -0,0 +1,80 @@
+package evaluator
+
+import (
+	"shanyl2400/go_compiler/ast"
+	"shanyl2400/go_compiler/object"
+)
+
+// evalThrowStatement 把任意值包装为错误抛出。
+// 字符串直接作为错误信息，带有字符串 message 字段的 Hash（例如 catch 得到的错误）取该字段。
+func (e *Evaluator) evalThrowStatement(ts *ast.ThrowStatement, env *object.Environment) object.Object {
+	val := e.eval(ts.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	var message string
+	switch val := val.(type) {
+	case *object.String:
+		message = val.Value
+	case *object.Hash:
+		message = val.Inspect()
+		if pair, ok := val.Get(&object.String{Value: "message"}); ok {
+			if msg, ok := pair.Value.(*object.String); ok {
+				message = msg.Value
+			}
+		}
+	default:
+		message = val.Inspect()
+	}
+	return &object.Error{Message: message, Value: val}
+}
+
+// evalTryExpression 执行 try 块，出错时执行 catch 块，最后总是执行 finally 块。
+// 超出执行限制的错误不能被捕获，finally 也不再执行。
+// catch 的参数只在 catch 块中可见。
+func (e *Evaluator) evalTryExpression(te *ast.TryExpression, env *object.Environment) object.Object {
+	result := e.eval(te.Block, env)
+
+	if errObj, ok := result.(*object.Error); ok && te.Catch != nil && e.run.halted == nil {
+		e.attachStack(errObj)
+
+		catchEnv := object.NewEnclosedEnviroment(env)
+		if te.Param != nil {
+			caught := e.track(e.errorValue(errObj))
+			if isError(caught) {
+				return caught
+			}
+			catchEnv.Set(te.Param.Value, caught)
+		}
+		result = e.eval(te.Catch, catchEnv)
+	}
+
+	if te.Finally != nil && e.run.halted == nil {
+		// finally 块出错或 return 时覆盖 try/catch 的结果
+		finally := e.eval(te.Finally, env)
+		if isError(finally) || (finally != nil && finally.Type() == object.RETURN_VALUE_OBJ) {
+			return finally
+		}
+	}
+	return result
+}
+
+// errorValue 把错误转换为脚本可以使用的 Hash：{"message": ..., "stack": [...], "value": ...}
+func (e *Evaluator) errorValue(errObj *object.Error) *object.Hash {
+	stack := make([]object.Object, len(errObj.Stack))
+	for i, frame := range errObj.Stack {
+		stack[i] = &object.String{Value: frame.String()}
+	}
+
+	value := errObj.Value
+	if value == nil {
+		value = NULL
+	}
+
+	hash := object.NewHash()
+	hash.Set(&object.String{Value: "message"}, &object.String{Value: errObj.Message})
+	hash.Set(&object.String{Value: "stack"}, &object.Array{Elements: stack})
+	hash.Set(&object.String{Value: "value"}, value)
+	return hash
+}
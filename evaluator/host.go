@@ -0,0 +1,33 @@
+package evaluator
+
+import (
+	"shanyl2400/go_compiler/object"
+)
+
+// evalHostMember 通过反射访问宿主对象的成员，方法返回绑定了接收者的内置函数
+func (e *Evaluator) evalHostMember(host *object.HostObject, name string) object.Object {
+	if !host.Allowed(name) {
+		return newError("member not accessible: %s.%s", host.Inspect(), name)
+	}
+
+	if method, ok := host.Method(name); ok {
+		fn, err := object.WrapFunc(host.Inspect()+"."+name, method.Interface())
+		if err != nil {
+			return newError("%s", err)
+		}
+		return &object.Builtin{Fn: fn}
+	}
+
+	if field, ok := host.Field(name); ok {
+		obj, err := object.FromGo(field.Interface())
+		if err != nil {
+			return newError("%s.%s: %s", host.Inspect(), name, err)
+		}
+		if err := e.alloc(object.DeepSize(obj)); err != nil {
+			return err
+		}
+		return obj
+	}
+
+	return newError("member not found: %s.%s", host.Inspect(), name)
+}
@@ -0,0 +1,128 @@
+package evaluator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"shanyl2400/go_compiler/ast"
+	"shanyl2400/go_compiler/object"
+	"time"
+)
+
+var (
+	ErrStepLimitExceeded = errors.New("step limit exceeded")
+	ErrCallDepthExceeded = errors.New("maximum recursion depth exceeded")
+)
+
+// DefaultMaxCallDepth 未设置 MaxCallDepth 时的调用深度上限。
+// 每次函数调用都会占用 Go 的栈，栈溢出无法 recover，所以调用深度总是有上限。
+const DefaultMaxCallDepth = 10000
+
+// checkInterval 每执行多少步检查一次 context（包括第一步），避免频繁加锁
+const checkInterval = 256
+
+// Limits 限制一次执行可以消耗的资源，除 MaxCallDepth 外零值表示不限制
+type Limits struct {
+	// MaxSteps 最多求值的节点数
+	MaxSteps int64
+	// MaxCallDepth 函数调用的最大嵌套层数，为零时使用 DefaultMaxCallDepth
+	MaxCallDepth int
+	// Timeout 单次执行的最长时间
+	Timeout time.Duration
+	// MaxMemory 数组、Hash 和字符串估算占用的字节数上限
+	MaxMemory int64
+}
+
+// runState 记录一次顶层执行的进度，每次 EvalContext 或 ApplyContext 开始时重置
+type runState struct {
+	ctx       context.Context
+	steps     int64
+	allocated int64
+
+	// frames 是当前的调用栈，第一层是顶层代码
+	frames []object.StackFrame
+
+	// loading 是正在加载的模块，用于检测循环导入和记录 export
+	loading []*moduleState
+
+	// halted 记录触发限制的错误，之后的求值直接返回它，保证执行尽快中止
+	halted *object.Error
+}
+
+func (e *Evaluator) SetLimits(limits Limits) {
+	e.limits = limits
+}
+
+// Eval 求值 node，不受 context 控制，但仍受 Limits 限制
+func (e *Evaluator) Eval(node ast.Node, env *object.Environment) object.Object {
+	return e.EvalContext(context.Background(), node, env)
+}
+
+// EvalContext 求值 node，ctx 取消、超时或超出 Limits 时返回带有 Err 的错误对象
+func (e *Evaluator) EvalContext(ctx context.Context, node ast.Node, env *object.Environment) object.Object {
+	cancel := e.begin(ctx)
+	defer cancel()
+	return e.attachStack(e.eval(node, env))
+}
+
+func (e *Evaluator) begin(ctx context.Context) context.CancelFunc {
+	cancel := func() {}
+	if e.limits.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, e.limits.Timeout)
+	}
+	e.run = runState{
+		ctx:    ctx,
+		frames: []object.StackFrame{{Function: "<main>"}},
+	}
+	return cancel
+}
+
+func (e *Evaluator) step() *object.Error {
+	if e.run.halted != nil {
+		return e.run.halted
+	}
+
+	e.run.steps++
+	if e.limits.MaxSteps > 0 && e.run.steps > e.limits.MaxSteps {
+		return e.halt(ErrStepLimitExceeded)
+	}
+	if e.run.ctx != nil && e.run.steps%checkInterval == 1 {
+		if err := e.run.ctx.Err(); err != nil {
+			return e.halt(err)
+		}
+	}
+	return nil
+}
+
+func (e *Evaluator) enterCall(fn *object.Function) *object.Error {
+	maxDepth := e.limits.MaxCallDepth
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxCallDepth
+	}
+
+	if len(e.run.frames) > maxDepth {
+		return e.halt(fmt.Errorf("%w in %s", ErrCallDepthExceeded, functionName(fn)))
+	}
+	e.run.frames = append(e.run.frames, object.StackFrame{
+		Function: functionName(fn),
+		File:     fn.File,
+		Line:     fn.Body.Token.Line,
+	})
+	return nil
+}
+
+func (e *Evaluator) leaveCall() {
+	e.run.frames = e.run.frames[:len(e.run.frames)-1]
+}
+
+func functionName(fn *object.Function) string {
+	if fn.Name == "" {
+		return "<anonymous>"
+	}
+	return fn.Name
+}
+
+func (e *Evaluator) halt(err error) *object.Error {
+	e.run.halted = &object.Error{Message: err.Error(), Err: err}
+	return e.run.halted
+}
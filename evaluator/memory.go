@@ -0,0 +1,40 @@
+package evaluator
+
+import (
+	"errors"
+	"fmt"
+	"shanyl2400/go_compiler/object"
+)
+
+var ErrMemoryLimitExceeded = errors.New("memory limit exceeded")
+
+// alloc 累计本次执行分配的字节数，超过 Limits.MaxMemory 时中止执行
+func (e *Evaluator) alloc(size int64) *object.Error {
+	if e.run.halted != nil {
+		return e.run.halted
+	}
+
+	e.run.allocated += size
+	if e.limits.MaxMemory > 0 && e.run.allocated > e.limits.MaxMemory {
+		err := fmt.Errorf("%w: allocated %d bytes, limit is %d bytes",
+			ErrMemoryLimitExceeded, e.run.allocated, e.limits.MaxMemory)
+		return e.halt(err)
+	}
+	return nil
+}
+
+// track 统计新创建对象的大小，超出限制时返回错误对象
+func (e *Evaluator) track(obj object.Object) object.Object {
+	if err := e.alloc(object.Size(obj)); err != nil {
+		return err
+	}
+	return obj
+}
+
+// track 供内置函数统计新创建的对象
+func track(ctx *object.Context, obj object.Object) object.Object {
+	if err := ctx.Alloc(object.Size(obj)); err != nil {
+		return err
+	}
+	return obj
+}
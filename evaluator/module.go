@@ -0,0 +1,211 @@
+package evaluator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"shanyl2400/go_compiler/ast"
+	"shanyl2400/go_compiler/lexer"
+	"shanyl2400/go_compiler/object"
+	"shanyl2400/go_compiler/parser"
+	"strings"
+)
+
+// ModuleExt 是模块文件的扩展名，import 时可以省略
+const ModuleExt = ".mk"
+
+type moduleState struct {
+	path    string
+	env     *object.Environment
+	exports []string
+}
+
+// SetSearchPath 设置查找模块的目录，按顺序查找，未设置时使用当前目录。
+// 以 ./ 或 ../ 开头的路径相对于导入它的文件所在目录。
+// 模块文件必须位于搜索路径中的目录或顶层文件所在的目录内，不能使用绝对路径。
+func (e *Evaluator) SetSearchPath(dirs ...string) {
+	e.searchPath = dirs
+}
+
+// DisableImports 禁止 import，执行不可信的代码时避免读取文件
+func (e *Evaluator) DisableImports() {
+	e.noImports = true
+}
+
+// evalImportExpression 在独立的环境中执行模块文件，同一个文件只执行一次
+func (e *Evaluator) evalImportExpression(ie *ast.ImportExpression) object.Object {
+	if e.noImports {
+		return newError("import is disabled: %s", ie.Path)
+	}
+	path, err := e.resolveModule(ie.Path)
+	if err != nil {
+		return newError("%s", err)
+	}
+	if module, ok := e.modules[path]; ok {
+		return module
+	}
+
+	chain := e.importChain()
+	for i, loading := range chain {
+		if loading == path {
+			cycle := append(chain[i:len(chain):len(chain)], path)
+			return newError("import cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return newError("cannot read module %s: %s", ie.Path, err)
+	}
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return newError("%s: parser errors: %s", path, strings.Join(p.Errors(), "; "))
+	}
+	program.File = path
+
+	state := &moduleState{path: path, env: object.NewEnvironment()}
+	e.run.loading = append(e.run.loading, state)
+	e.run.frames = append(e.run.frames, object.StackFrame{Function: "<module>", File: path})
+	defer func() {
+		e.run.loading = e.run.loading[:len(e.run.loading)-1]
+		e.run.frames = e.run.frames[:len(e.run.frames)-1]
+	}()
+
+	result := e.eval(program, state.env)
+	if isError(result) {
+		return e.attachStack(result)
+	}
+
+	exports := object.NewHash()
+	for _, name := range state.exports {
+		val, _ := state.env.Get(name)
+		exports.Set(&object.String{Value: name}, val)
+	}
+	module := &object.Module{Path: path, Exports: exports}
+	e.modules[path] = module
+	return module
+}
+
+// evalExportStatement 与 let 相同，在模块顶层执行时额外记录导出的名字
+func (e *Evaluator) evalExportStatement(es *ast.ExportStatement, env *object.Environment) object.Object {
+	val := e.evalLetStatement(es.Statement, env)
+	if isError(val) {
+		return val
+	}
+
+	if n := len(e.run.loading); n > 0 && e.run.loading[n-1].env == env {
+		state := e.run.loading[n-1]
+		state.exports = append(state.exports, es.Statement.Name.Value)
+	}
+	return val
+}
+
+// importChain 返回从顶层文件开始、正在加载的模块路径
+func (e *Evaluator) importChain() []string {
+	chain := make([]string, 0, len(e.run.loading)+1)
+	if root := e.run.frames[0].File; root != "" {
+		if abs, err := filepath.Abs(root); err == nil {
+			chain = append(chain, abs)
+		}
+	}
+	for _, m := range e.run.loading {
+		chain = append(chain, m.path)
+	}
+	return chain
+}
+
+func evalModuleMember(module *object.Module, name string) object.Object {
+	pair, ok := module.Exports.Get(&object.String{Value: name})
+	if !ok {
+		return newError("%s is not exported by module %s", name, module.Path)
+	}
+	return pair.Value
+}
+
+// resolveModule 返回模块文件的绝对路径，作为缓存的键
+func (e *Evaluator) resolveModule(name string) (string, error) {
+	if filepath.IsAbs(name) {
+		return "", fmt.Errorf("import path must be relative: %s", name)
+	}
+
+	var dirs []string
+	switch {
+	case strings.HasPrefix(name, "./") || strings.HasPrefix(name, "../"):
+		dirs = []string{filepath.Dir(e.currentFrame().File)}
+	case len(e.searchPath) > 0:
+		dirs = e.searchPath
+	default:
+		dirs = []string{"."}
+	}
+
+	roots := e.importRoots()
+	outside := false
+	for _, dir := range dirs {
+		for _, candidate := range []string{name, name + ModuleExt} {
+			path, err := filepath.Abs(filepath.Join(dir, candidate))
+			if err != nil {
+				continue
+			}
+			// 先检查路径再访问文件，不暴露目录之外的文件是否存在
+			if !within(path, roots) {
+				outside = true
+				continue
+			}
+			if info, err := os.Stat(path); err != nil || info.IsDir() {
+				continue
+			}
+			// 符号链接可能指向目录之外
+			if real, err := filepath.EvalSymlinks(path); err != nil || !within(real, realPaths(roots)) {
+				outside = true
+				continue
+			}
+			return path, nil
+		}
+	}
+	if outside {
+		return "", fmt.Errorf("module outside the search path: %s", name)
+	}
+	return "", fmt.Errorf("module not found: %s", name)
+}
+
+// importRoots 返回模块文件可以位于的目录：搜索路径（未设置时为当前目录）和顶层文件所在的目录
+func (e *Evaluator) importRoots() []string {
+	dirs := e.searchPath
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+	if root := e.run.frames[0].File; root != "" {
+		dirs = append(dirs[:len(dirs):len(dirs)], filepath.Dir(root))
+	}
+
+	roots := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		if abs, err := filepath.Abs(dir); err == nil {
+			roots = append(roots, abs)
+		}
+	}
+	return roots
+}
+
+func realPaths(paths []string) []string {
+	real := make([]string, len(paths))
+	for i, path := range paths {
+		real[i] = path
+		if r, err := filepath.EvalSymlinks(path); err == nil {
+			real[i] = r
+		}
+	}
+	return real
+}
+
+// within 判断 path 是否位于 roots 中某个目录内，两者都是绝对路径
+func within(path string, roots []string) bool {
+	for _, root := range roots {
+		rel, err := filepath.Rel(root, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,31 @@
+package evaluator
+
+import (
+	"shanyl2400/go_compiler/ast"
+	"shanyl2400/go_compiler/object"
+)
+
+func (e *Evaluator) currentFrame() *object.StackFrame {
+	return &e.run.frames[len(e.run.frames)-1]
+}
+
+// trackLine 在执行语句和函数调用时记录当前函数执行到的行
+func (e *Evaluator) trackLine(node ast.Node) {
+	switch node.(type) {
+	case *ast.BlockStatement:
+		return
+	case ast.Statement, *ast.CallExpression:
+		e.currentFrame().Line = ast.TokenOf(node).Line
+	}
+}
+
+// attachStack 给还没有调用栈的错误记录当前调用栈，最内层在前
+func (e *Evaluator) attachStack(obj object.Object) object.Object {
+	errObj, ok := obj.(*object.Error)
+	if !ok || errObj.Stack != nil {
+		return obj
+	}
+
+	errObj.Stack = e.Stack()
+	return errObj
+}
@@ -0,0 +1,290 @@
+// Package format 根据语法树格式化源码
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"shanyl2400/go_compiler/ast"
+	"shanyl2400/go_compiler/lexer"
+	"shanyl2400/go_compiler/parser"
+	"shanyl2400/go_compiler/token"
+	"strings"
+)
+
+// Indent 是每层代码块的缩进
+const Indent = "    "
+
+// Source 格式化源码，源码无法解析时返回错误
+func Source(src []byte) ([]byte, error) {
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("parser errors: %s", strings.Join(p.Errors(), "; "))
+	}
+	return Program(program, src), nil
+}
+
+// Program 格式化语法树。src 是解析 program 的源码，用于保留语句之间的空行，可以为 nil
+func Program(program *ast.Program, src []byte) []byte {
+	p := &printer{
+		comments: program.Comments,
+		first:    true,
+	}
+	if src != nil {
+		p.lines = strings.Split(string(src), "\n")
+	}
+
+	p.statements(program.Statements)
+	p.flushComments(token.Token{Line: math.MaxInt})
+	if p.out.Len() > 0 {
+		p.out.WriteByte('\n')
+	}
+	return p.out.Bytes()
+}
+
+type printer struct {
+	out   bytes.Buffer
+	lines []string
+	depth int
+
+	// comments 中 next 之前的注释已经输出
+	comments []*ast.Comment
+	next     int
+
+	// first 表示下一项是代码块中的第一项，前面不加空行
+	first bool
+}
+
+func (p *printer) statements(stmts []ast.Statement) {
+	for i, stmt := range stmts {
+		start := ast.TokenOf(stmt)
+		p.flushComments(start)
+		p.linebreak(start.Line)
+		p.statement(stmt)
+
+		if expr, ok := stmt.(*ast.ExpressionStatement); ok {
+			var next ast.Statement
+			if i+1 < len(stmts) {
+				next = stmts[i+1]
+			}
+			if needsSemicolon(expr, next) {
+				p.out.WriteString(";")
+			}
+		}
+	}
+}
+
+// flushComments 输出位于 pos 之前的注释，行尾注释仍然留在行尾
+func (p *printer) flushComments(pos token.Token) {
+	for p.next < len(p.comments) && before(p.comments[p.next].Token, pos) {
+		c := p.comments[p.next]
+		p.next++
+
+		if c.Trailing && p.out.Len() > 0 {
+			p.out.WriteString(" " + c.Text())
+			p.first = false
+			continue
+		}
+		p.linebreak(c.Token.Line)
+		p.out.WriteString(c.Text())
+	}
+}
+
+// linebreak 开始新的一行，源码中 line 前是空行时保留一个空行
+func (p *printer) linebreak(line int) {
+	if p.out.Len() > 0 {
+		p.out.WriteByte('\n')
+		if !p.first && p.blankBefore(line) {
+			p.out.WriteByte('\n')
+		}
+	}
+	p.first = false
+	p.out.WriteString(strings.Repeat(Indent, p.depth))
+}
+
+func (p *printer) blankBefore(line int) bool {
+	if line < 2 || line-2 >= len(p.lines) {
+		return false
+	}
+	return strings.TrimSpace(p.lines[line-2]) == ""
+}
+
+func (p *printer) statement(stmt ast.Statement) {
+	switch s := stmt.(type) {
+	case *ast.LetStatement:
+		p.let(s)
+	case *ast.ExportStatement:
+		p.out.WriteString("export ")
+		p.let(s.Statement)
+	case *ast.ReturnStatement:
+		p.out.WriteString("return")
+		if s.Value != nil {
+			p.out.WriteString(" ")
+			p.expr(s.Value, parser.LOWEST)
+		}
+		p.out.WriteString(";")
+	case *ast.ThrowStatement:
+		p.out.WriteString("throw ")
+		p.expr(s.Value, parser.LOWEST)
+		p.out.WriteString(";")
+	case *ast.WhileStatement:
+		p.out.WriteString("while (")
+		p.expr(s.Condition, parser.LOWEST)
+		p.out.WriteString(") ")
+		p.block(s.Consequence)
+	case *ast.ExpressionStatement:
+		p.expr(s.Expression, parser.LOWEST)
+	case *ast.BlockStatement:
+		p.block(s)
+	}
+}
+
+func (p *printer) let(s *ast.LetStatement) {
+	p.out.WriteString("let " + s.Name.Value + " = ")
+	p.expr(s.Value, parser.LOWEST)
+	p.out.WriteString(";")
+}
+
+func (p *printer) block(b *ast.BlockStatement) {
+	p.out.WriteString("{")
+	p.depth++
+	p.first = true
+
+	p.statements(b.Statements)
+	p.flushComments(b.End)
+
+	p.depth--
+	if p.first {
+		p.out.WriteString("}")
+	} else {
+		p.out.WriteString("\n" + strings.Repeat(Indent, p.depth) + "}")
+	}
+	p.first = false
+}
+
+// expr 输出表达式，优先级低于 min 时加括号
+func (p *printer) expr(e ast.Expression, min int) {
+	if precedence(e) < min {
+		p.out.WriteString("(")
+		defer p.out.WriteString(")")
+	}
+
+	switch e := e.(type) {
+	case *ast.Identifier:
+		p.out.WriteString(e.Value)
+	case *ast.IntegerLiteral, *ast.Boolean, *ast.NullLiteral:
+		p.out.WriteString(e.TokenLiteral())
+	case *ast.StringLiteral:
+		p.out.WriteString(`"` + e.Value + `"`)
+	case *ast.ArrayLiteral:
+		p.out.WriteString("[")
+		p.exprList(e.Elements)
+		p.out.WriteString("]")
+	case *ast.HashLiteral:
+		p.out.WriteString("{")
+		for i, key := range e.Keys {
+			if i > 0 {
+				p.out.WriteString(", ")
+			}
+			p.expr(key, parser.LOWEST)
+			p.out.WriteString(": ")
+			p.expr(e.Pairs[key], parser.LOWEST)
+		}
+		p.out.WriteString("}")
+	case *ast.PrefixExpression:
+		p.out.WriteString(e.Operator)
+		p.expr(e.Right, parser.PREFIX)
+	case *ast.InfixExpression:
+		prec := precedence(e)
+		p.expr(e.Left, prec)
+		p.out.WriteString(" " + e.Operator + " ")
+		p.expr(e.Right, prec+1)
+	case *ast.CallExpression:
+		p.expr(e.Function, parser.CALL)
+		p.out.WriteString("(")
+		p.exprList(e.Arguments)
+		p.out.WriteString(")")
+	case *ast.IndexExpression:
+		p.expr(e.Left, parser.INDEX)
+		p.out.WriteString(e.TokenLiteral())
+		p.expr(e.Index, parser.LOWEST)
+		p.out.WriteString("]")
+	case *ast.MemberExpression:
+		p.expr(e.Object, parser.INDEX)
+		p.out.WriteString(e.TokenLiteral() + e.Property.Value)
+	case *ast.FunctionLiteral:
+		p.out.WriteString("fn(")
+		for i, param := range e.Parameters {
+			if i > 0 {
+				p.out.WriteString(", ")
+			}
+			p.out.WriteString(param.Value)
+		}
+		p.out.WriteString(") ")
+		p.block(e.Body)
+	case *ast.IfExpression:
+		p.out.WriteString("if (")
+		p.expr(e.Condition, parser.LOWEST)
+		p.out.WriteString(") ")
+		p.block(e.Consequence)
+		if e.Alternative != nil {
+			p.out.WriteString(" else ")
+			p.block(e.Alternative)
+		}
+	case *ast.TryExpression:
+		p.out.WriteString("try ")
+		p.block(e.Block)
+		if e.Catch != nil {
+			p.out.WriteString(" catch ")
+			if e.Param != nil {
+				p.out.WriteString("(" + e.Param.Value + ") ")
+			}
+			p.block(e.Catch)
+		}
+		if e.Finally != nil {
+			p.out.WriteString(" finally ")
+			p.block(e.Finally)
+		}
+	case *ast.ImportExpression:
+		p.out.WriteString(`import "` + e.Path + `"`)
+	}
+}
+
+func (p *printer) exprList(exps []ast.Expression) {
+	for i, e := range exps {
+		if i > 0 {
+			p.out.WriteString(", ")
+		}
+		p.expr(e, parser.LOWEST)
+	}
+}
+
+// precedence 返回表达式作为操作数时的优先级，字面量等不需要括号的表达式最高
+func precedence(e ast.Expression) int {
+	switch e := e.(type) {
+	case *ast.InfixExpression:
+		return parser.Precedence(e.Token.Type)
+	case *ast.PrefixExpression:
+		return parser.PREFIX
+	case *ast.CallExpression:
+		return parser.CALL
+	case *ast.IndexExpression, *ast.MemberExpression:
+		return parser.INDEX
+	}
+	return parser.INDEX + 1
+}
+
+// needsSemicolon 判断表达式语句后是否需要分号。
+// if 和 try 以代码块结尾，只有下一条语句以中缀运算符开头时才需要分号，否则会被解析为一个表达式。
+func needsSemicolon(stmt *ast.ExpressionStatement, next ast.Statement) bool {
+	switch stmt.Expression.(type) {
+	case *ast.IfExpression, *ast.TryExpression:
+		return next != nil && parser.Precedence(ast.TokenOf(next).Type) > parser.LOWEST
+	}
+	return true
+}
+
+func before(a, b token.Token) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+}
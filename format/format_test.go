@@ -0,0 +1,79 @@
+package format
+
+import (
+	"shanyl2400/go_compiler/lexer"
+	"shanyl2400/go_compiler/parser"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x=1", "let x = 1;\n"},
+		{"let add=fn(a,b){a+b};", "let add = fn(a, b) {\n    a + b;\n};\n"},
+		{"(1 + 2) * 3 - (4 - 5)", "(1 + 2) * 3 - (4 - 5);\n"},
+		{"((a * b)) + c", "a * b + c;\n"},
+		{"-(a + b); !(-a)", "-(a + b);\n!-a;\n"},
+		{`{"a":1,"b":[1,2]}["b"][0]`, "{\"a\": 1, \"b\": [1, 2]}[\"b\"][0];\n"},
+		{"a?.b?[0].c ?? d", "a?.b?[0].c ?? d;\n"},
+		{"(a ?? b).c(1,2)", "(a ?? b).c(1, 2);\n"},
+		{"fn(x){x}(1)", "fn(x) {\n    x;\n}(1);\n"},
+		{"if(a){1}else{2}", "if (a) {\n    1;\n} else {\n    2;\n}\n"},
+		{"if(a){1};-1", "if (a) {\n    1;\n};\n-1;\n"},
+		{"while(a<3){let a=a+1;}", "while (a < 3) {\n    let a = a + 1;\n}\n"},
+		{`try{throw "e"}catch(e){e.message}finally{1}`,
+			"try {\n    throw \"e\";\n} catch (e) {\n    e.message;\n} finally {\n    1;\n}\n"},
+		{`let m=import "lib/m";export let x=m.x;`, "let m = import \"lib/m\";\nexport let x = m.x;\n"},
+		{"let f = fn() {}", "let f = fn() {};\n"},
+		// 空行最多保留一个，代码块开头和结尾的空行去掉
+		{"let a = 1;\n\n\n\nlet b = 2;", "let a = 1;\n\nlet b = 2;\n"},
+		{"fn() {\n\n  a;\n\n  b;\n\n}", "fn() {\n    a;\n\n    b;\n};\n"},
+		// 注释
+		{"// head\nlet a = 1; // one\n\n// two\nlet b = 2;\n// tail",
+			"// head\nlet a = 1; // one\n\n// two\nlet b = 2;\n// tail\n"},
+		{"let f = fn() { // open\n  // inner\n  a // last\n  // end\n};",
+			"let f = fn() { // open\n    // inner\n    a; // last\n    // end\n};\n"},
+		{"let f = fn() {\n// only\n};", "let f = fn() {\n    // only\n};\n"},
+		{"// only comment", "// only comment\n"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		out, err := Source([]byte(tt.input))
+		if err != nil {
+			t.Fatalf("format %q failed: %s", tt.input, err)
+		}
+		if string(out) != tt.expected {
+			t.Errorf("wrong format for %q.\nexpected=%q\ngot=     %q", tt.input, tt.expected, out)
+		}
+
+		again, err := Source(out)
+		if err != nil {
+			t.Fatalf("formatted output of %q does not parse: %s", tt.input, err)
+		}
+		if string(again) != string(out) {
+			t.Errorf("format is not idempotent for %q.\nfirst= %q\nsecond=%q", tt.input, out, again)
+		}
+
+		if before, after := parse(t, tt.input), parse(t, string(out)); before != after {
+			t.Errorf("format changed program %q.\nbefore=%q\nafter= %q", tt.input, before, after)
+		}
+	}
+}
+
+func TestSourceParseError(t *testing.T) {
+	if _, err := Source([]byte("let = 1;")); err == nil {
+		t.Errorf("expected error for invalid source")
+	}
+}
+
+func parse(t *testing.T, input string) string {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return program.String()
+}
@@ -0,0 +1,49 @@
+// Package framing 实现 LSP 和 DAP 共用的消息格式：Content-Length 头、空行，然后是 JSON 正文
+package framing
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+)
+
+// MaxContentLength 是一条消息正文的最大字节数，避免错误或恶意的长度导致分配过多内存
+const MaxContentLength = 64 << 20
+
+// Read 读取一条消息的正文，长度无效或超过 MaxContentLength 时返回错误
+func Read(r *bufio.Reader) ([]byte, error) {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	value := header.Get("Content-Length")
+	length, err := strconv.Atoi(value)
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length: %q", value)
+	}
+	if length > MaxContentLength {
+		return nil, fmt.Errorf("Content-Length %d exceeds limit of %d bytes", length, MaxContentLength)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// Write 把 v 编码为 JSON 并写入一条消息
+func Write(w io.Writer, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = w.Write(body)
+	return err
+}
@@ -0,0 +1,47 @@
+package framing
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadWrite(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, map[string]int{"seq": 1}))
+	require.NoError(t, Write(&buf, []string{"a"}))
+	assert.Equal(t, "Content-Length: 9\r\n\r\n{\"seq\":1}Content-Length: 5\r\n\r\n[\"a\"]", buf.String())
+
+	r := bufio.NewReader(&buf)
+	body, err := Read(r)
+	require.NoError(t, err)
+	assert.Equal(t, `{"seq":1}`, string(body))
+	body, err = Read(r)
+	require.NoError(t, err)
+	assert.Equal(t, `["a"]`, string(body))
+	_, err = Read(r)
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestReadInvalid(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Content-Length: -1\r\n\r\n", `invalid Content-Length: "-1"`},
+		{"Content-Length: abc\r\n\r\n", `invalid Content-Length: "abc"`},
+		{"Content-Type: json\r\n\r\n{}", `invalid Content-Length: ""`},
+		{"Content-Length: 99999999999\r\n\r\n", "Content-Length 99999999999 exceeds limit of 67108864 bytes"},
+		{"Content-Length: 10\r\n\r\n{}", "unexpected EOF"},
+	}
+
+	for _, tt := range tests {
+		_, err := Read(bufio.NewReader(strings.NewReader(tt.input)))
+		assert.EqualError(t, err, tt.expected, tt.input)
+	}
+}
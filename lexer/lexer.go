@@ -2,6 +2,7 @@ package lexer
 
 import (
 	"shanyl2400/go_compiler/token"
+	"strings"
 )
 
 type Lexer struct {
@@ -10,12 +11,35 @@ type Lexer struct {
 	readPosition int
 
 	ch byte
+
+	// line 是当前字节所在行，lineStart 是该行第一个字节的位置
+	line      int
+	lineStart int
+
+	// comments 保存跳过的注释
+	comments []token.Token
 }
 
 func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
-
 	l.skipWhiteSpace()
+	for l.ch == '/' && l.peekChar() == '/' {
+		l.readComment()
+		l.skipWhiteSpace()
+	}
+
+	line, column := l.line, l.position-l.lineStart+1
+	tok := l.nextToken()
+	tok.Line, tok.Column = line, column
+	return tok
+}
+
+// Comments 返回目前为止读到的注释，类型为 token.COMMENT，Literal 包括开头的 //
+func (l *Lexer) Comments() []token.Token {
+	return l.comments
+}
+
+func (l *Lexer) nextToken() token.Token {
+	var tok token.Token
 
 	switch l.ch {
 	case '=':
@@ -73,6 +97,19 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.COLON, l.ch)
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
+	case '.':
+		tok = newToken(token.DOT, l.ch)
+	case '?':
+		switch l.peekChar() {
+		case '?':
+			tok = l.readTwoCharToken(token.NULLISH)
+		case '.':
+			tok = l.readTwoCharToken(token.OPT_DOT)
+		case '[':
+			tok = l.readTwoCharToken(token.OPT_LBRACKET)
+		default:
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
 	case '"':
 		tok.Type = token.STRING
 		tok.Literal = l.readString()
@@ -99,6 +136,10 @@ func (l *Lexer) NextToken() token.Token {
 
 // readChar 读取下一个字节
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.lineStart = l.readPosition
+	}
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
@@ -108,6 +149,16 @@ func (l *Lexer) readChar() {
 	l.readPosition++
 }
 
+// readTwoCharToken 读取由当前字节和下一个字节组成的 token
+func (l *Lexer) readTwoCharToken(tokenType token.TokenType) token.Token {
+	ch := l.ch
+	l.readChar()
+	return token.Token{
+		Type:    tokenType,
+		Literal: string(ch) + string(l.ch),
+	}
+}
+
 // peekChar 窥探下一个字节，不移动指针
 func (l *Lexer) peekChar() byte {
 	if l.readPosition >= len(l.input) {
@@ -138,6 +189,22 @@ func (l *Lexer) skipWhiteSpace() {
 	}
 }
 
+// readComment 读取 // 开始到行尾的注释
+func (l *Lexer) readComment() {
+	comment := token.Token{
+		Type:   token.COMMENT,
+		Line:   l.line,
+		Column: l.position - l.lineStart + 1,
+	}
+
+	position := l.position
+	for l.ch != '\n' && l.ch != 0 {
+		l.readChar()
+	}
+	comment.Literal = strings.TrimRight(l.input[position:l.position], "\r")
+	l.comments = append(l.comments, comment)
+}
+
 func (l *Lexer) readString() string {
 	position := l.position + 1
 
@@ -174,6 +241,7 @@ func isDigit(ch byte) bool {
 func New(input string) *Lexer {
 	l := &Lexer{
 		input: input,
+		line:  1,
 	}
 	l.readChar()
 	return l
@@ -0,0 +1,99 @@
+// Package lint 检查语法正确但很可能有错误的代码
+package lint
+
+import (
+	"fmt"
+	"shanyl2400/go_compiler/ast"
+	"shanyl2400/go_compiler/token"
+	"sort"
+)
+
+// Diagnostic 是一条检查结果，Line 和 Column 从 1 开始
+type Diagnostic struct {
+	Rule    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s (%s)", d.Line, d.Column, d.Message, d.Rule)
+}
+
+// Rule 是一条检查规则，通过 Pass.Reportf 报告问题
+type Rule interface {
+	Name() string
+	Check(pass *Pass)
+}
+
+type ruleFunc struct {
+	name  string
+	check func(pass *Pass)
+}
+
+func (r *ruleFunc) Name() string {
+	return r.name
+}
+
+func (r *ruleFunc) Check(pass *Pass) {
+	r.check(pass)
+}
+
+// NewRule 用函数创建规则
+func NewRule(name string, check func(pass *Pass)) Rule {
+	return &ruleFunc{name: name, check: check}
+}
+
+// Pass 是一条规则检查一个程序时的上下文
+type Pass struct {
+	Program *ast.Program
+
+	rule        string
+	diagnostics []Diagnostic
+	scopes      *resolution
+}
+
+// Reportf 在 tok 的位置报告问题
+func (p *Pass) Reportf(tok token.Token, format string, args ...any) {
+	p.diagnostics = append(p.diagnostics, Diagnostic{
+		Rule:    p.rule,
+		Line:    tok.Line,
+		Column:  tok.Column,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// resolution 返回程序中的绑定和标识符引用，多条规则共享同一份结果
+func (p *Pass) resolution() *resolution {
+	if p.scopes == nil {
+		p.scopes = resolve(p.Program)
+	}
+	return p.scopes
+}
+
+// DefaultRules 是 interpreter lint 默认启用的规则
+var DefaultRules = []Rule{
+	UnusedRule,
+	ShadowRule,
+	ArityRule,
+	UnreachableRule,
+	ConstantConditionRule,
+}
+
+// Run 用 rules 检查 program，结果按位置排序
+func Run(program *ast.Program, rules ...Rule) []Diagnostic {
+	pass := &Pass{Program: program}
+	for _, rule := range rules {
+		pass.rule = rule.Name()
+		rule.Check(pass)
+	}
+
+	sort.SliceStable(pass.diagnostics, func(i, j int) bool {
+		a, b := pass.diagnostics[i], pass.diagnostics[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return pass.diagnostics
+}
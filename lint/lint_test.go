@@ -0,0 +1,91 @@
+package lint
+
+import (
+	"reflect"
+	"shanyl2400/go_compiler/ast"
+	"shanyl2400/go_compiler/lexer"
+	"shanyl2400/go_compiler/parser"
+	"testing"
+)
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		rule     Rule
+		input    string
+		expected []string
+	}{
+		{UnusedRule, "let a = 1; let b = 2; puts(b);", []string{"1:5: a declared and not used (unused)"}},
+		{UnusedRule, "let a = 1; let a = a + 1;", nil},
+		{UnusedRule, "let f = fn() { g() }; let g = fn() { 1 }; f();", nil},
+		{UnusedRule, "let f = fn(x) { let y = 1; 2 }; f(1);", []string{"1:21: y declared and not used (unused)"}},
+		{UnusedRule, "export let a = 1; let _ = 2;", nil},
+		{UnusedRule, "if (true) { let a = 1; } a;", nil},
+		{UnusedRule, "try { let a = 1 } catch (e) { let b = 2 }", []string{
+			"1:11: a declared and not used (unused)",
+			"1:35: b declared and not used (unused)",
+		}},
+		{UnusedRule, "let a = 1; try { 1 } catch (a) { 2 }", []string{"1:5: a declared and not used (unused)"}},
+
+		{ShadowRule, "let x = 1; let f = fn(x) { x }; f(x);", []string{"1:23: x shadows declaration at line 1 (shadow)"}},
+		{ShadowRule, "let x = 1;\nlet f = fn() { let x = 2; x };", []string{"2:20: x shadows declaration at line 1 (shadow)"}},
+		{ShadowRule, "let x = 1; let x = 2; if (x) { let x = 3 }", nil},
+		{ShadowRule, "let e = 1; try { 1 } catch (e) { e }", []string{"1:29: e shadows declaration at line 1 (shadow)"}},
+
+		{ArityRule, `len("a", "b"); len("a"); puts(); merge();`, []string{
+			"1:1: wrong number of arguments to len. got=2, want=1 (arity)",
+			"1:34: wrong number of arguments to merge. got=0, want>=1 (arity)",
+		}},
+		{ArityRule, "let add = fn(a, b) { a + b }; add(1); add(1, 2);", []string{
+			"1:31: wrong number of arguments to add. got=1, want=2 (arity)",
+		}},
+		// 重新赋值或被遮蔽的名字不检查
+		{ArityRule, "let add = fn(a, b) { a + b }; let add = fn(a) { a }; add(1);", nil},
+		{ArityRule, "let len = fn(a, b) { a }; len(1, 2);", nil},
+
+		{UnreachableRule, "let f = fn() { return 1; puts(1); puts(2); };", []string{"1:26: unreachable code (unreachable)"}},
+		{UnreachableRule, "let f = fn() { if (a) { throw 1 } puts(1) };", nil},
+		{UnreachableRule, "return 1; 2", []string{"1:11: unreachable code (unreachable)"}},
+
+		{ConstantConditionRule, "if (true) { 1 }", []string{"1:5: condition is always true (constant-condition)"}},
+		{ConstantConditionRule, "if (1 < 2) { 1 } else { 2 }", []string{"1:5: condition is constant (constant-condition)"}},
+		{ConstantConditionRule, "while (false) { 1 }", []string{"1:8: condition is always false (constant-condition)"}},
+		{ConstantConditionRule, "while (true) { return 1 }", nil},
+		{ConstantConditionRule, "if (a < 2) { 1 }", nil},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parser errors for %q: %v", tt.input, p.Errors())
+		}
+
+		var got []string
+		for _, d := range Run(program, tt.rule) {
+			got = append(got, d.String())
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("wrong diagnostics for %q.\nexpected=%q\ngot=     %q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestCustomRule(t *testing.T) {
+	program := parser.New(lexer.New("let a = 1;\nlet b = a;")).ParseProgram()
+
+	noLet := NewRule("no-let", func(pass *Pass) {
+		for _, stmt := range pass.Program.Statements {
+			pass.Reportf(ast.TokenOf(stmt), "let is not allowed")
+		}
+	})
+
+	diagnostics := Run(program, noLet, UnusedRule)
+	expected := []Diagnostic{
+		{Rule: "no-let", Line: 1, Column: 1, Message: "let is not allowed"},
+		{Rule: "no-let", Line: 2, Column: 1, Message: "let is not allowed"},
+		{Rule: "unused", Line: 2, Column: 5, Message: "b declared and not used"},
+	}
+	if !reflect.DeepEqual(diagnostics, expected) {
+		t.Errorf("wrong diagnostics.\nexpected=%+v\ngot=     %+v", expected, diagnostics)
+	}
+}
@@ -0,0 +1,141 @@
+package lint
+
+import (
+	"shanyl2400/go_compiler/ast"
+	"shanyl2400/go_compiler/evaluator"
+)
+
+var (
+	// UnusedRule 报告声明后从未读取的 let 绑定，export 的绑定和 _ 除外
+	UnusedRule = NewRule("unused", checkUnused)
+	// ShadowRule 报告遮蔽外层作用域中同名绑定的声明
+	ShadowRule = NewRule("shadow", checkShadow)
+	// ArityRule 报告参数个数错误的内置函数调用和函数调用
+	ArityRule = NewRule("arity", checkArity)
+	// UnreachableRule 报告 return 和 throw 之后的语句
+	UnreachableRule = NewRule("unreachable", checkUnreachable)
+	// ConstantConditionRule 报告条件恒定的 if 和 while，while (true) 除外
+	ConstantConditionRule = NewRule("constant-condition", checkConstantCondition)
+)
+
+func checkUnused(pass *Pass) {
+	for _, b := range pass.resolution().bindings {
+		if b.Kind == LetBinding && !b.used && !b.exported && b.Name != "_" {
+			pass.Reportf(b.Decl.Token, "%s declared and not used", b.Name)
+		}
+	}
+}
+
+func checkShadow(pass *Pass) {
+	for _, b := range pass.resolution().bindings {
+		if b.shadows != nil {
+			pass.Reportf(b.Decl.Token, "%s shadows declaration at line %d", b.Name, b.shadows.Decl.Token.Line)
+		}
+	}
+}
+
+func checkArity(pass *Pass) {
+	refs := pass.resolution().refs
+
+	ast.Inspect(pass.Program, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpression)
+		if !ok {
+			return true
+		}
+		ident, ok := call.Function.(*ast.Identifier)
+		if !ok {
+			return true
+		}
+
+		got := len(call.Arguments)
+		if b, ok := refs[ident]; ok {
+			if b.fn != nil && got != len(b.fn.Parameters) {
+				pass.Reportf(ident.Token, "wrong number of arguments to %s. got=%d, want=%d",
+					ident.Value, got, len(b.fn.Parameters))
+			}
+			return true
+		}
+
+		want, ok := evaluator.BuiltinArity(ident.Value)
+		if !ok {
+			return true
+		}
+		switch {
+		case want.Max == -1 && got < want.Min:
+			pass.Reportf(ident.Token, "wrong number of arguments to %s. got=%d, want>=%d",
+				ident.Value, got, want.Min)
+		case want.Max != -1 && (got < want.Min || got > want.Max):
+			pass.Reportf(ident.Token, "wrong number of arguments to %s. got=%d, want=%d",
+				ident.Value, got, want.Min)
+		}
+		return true
+	})
+}
+
+func checkUnreachable(pass *Pass) {
+	check := func(stmts []ast.Statement) {
+		for i := 0; i+1 < len(stmts); i++ {
+			switch stmts[i].(type) {
+			case *ast.ReturnStatement, *ast.ThrowStatement:
+				pass.Reportf(ast.TokenOf(stmts[i+1]), "unreachable code")
+				return
+			}
+		}
+	}
+
+	ast.Inspect(pass.Program, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Program:
+			check(n.Statements)
+		case *ast.BlockStatement:
+			check(n.Statements)
+		}
+		return true
+	})
+}
+
+func checkConstantCondition(pass *Pass) {
+	ast.Inspect(pass.Program, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.IfExpression:
+			reportConstant(pass, n.Condition)
+		case *ast.WhileStatement:
+			if b, ok := n.Condition.(*ast.Boolean); ok && b.Value {
+				return true
+			}
+			reportConstant(pass, n.Condition)
+		}
+		return true
+	})
+}
+
+func reportConstant(pass *Pass, cond ast.Expression) {
+	if !isConstant(cond) {
+		return
+	}
+	if b, ok := cond.(*ast.Boolean); ok {
+		pass.Reportf(b.Token, "condition is always %t", b.Value)
+		return
+	}
+	pass.Reportf(expressionToken(cond), "condition is constant")
+}
+
+// isConstant 判断表达式是否只由字面量和运算符组成
+func isConstant(e ast.Expression) bool {
+	switch e := e.(type) {
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean, *ast.NullLiteral, *ast.FunctionLiteral:
+		return true
+	case *ast.PrefixExpression:
+		return isConstant(e.Right)
+	case *ast.InfixExpression:
+		return isConstant(e.Left) && isConstant(e.Right)
+	case *ast.ArrayLiteral:
+		for _, elem := range e.Elements {
+			if !isConstant(elem) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,274 @@
+package lint
+
+import (
+	"shanyl2400/go_compiler/ast"
+	"shanyl2400/go_compiler/token"
+	"sort"
+)
+
+type BindingKind int
+
+const (
+	LetBinding BindingKind = iota
+	ParamBinding
+	CatchBinding
+)
+
+func (k BindingKind) String() string {
+	switch k {
+	case ParamBinding:
+		return "parameter"
+	case CatchBinding:
+		return "catch parameter"
+	}
+	return "let"
+}
+
+// Binding 是作用域中的一个名字，同一作用域中重复的 let 只是重新赋值
+type Binding struct {
+	Name string
+	Kind BindingKind
+	// Decl 是第一次声明该名字的标识符
+	Decl *ast.Identifier
+	// Value 是第一次 let 的值，参数为 nil
+	Value ast.Expression
+
+	exported bool
+	used     bool
+
+	// assigns 是同一作用域中 let 该名字的次数，只赋值一次的函数才能检查参数个数
+	assigns int
+	fn      *ast.FunctionLiteral
+
+	// shadows 是被遮蔽的外层绑定
+	shadows *Binding
+}
+
+// scope 对应求值器中的一个 Environment：程序顶层、函数体和 catch 块。
+// start 和 end 是代码块的 { 和 }，顶层作用域为零值。
+type scope struct {
+	outer    *scope
+	bindings map[string]*Binding
+
+	start, end token.Token
+}
+
+func (s *scope) lookup(name string) *Binding {
+	for ; s != nil; s = s.outer {
+		if b, ok := s.bindings[name]; ok {
+			return b
+		}
+	}
+	return nil
+}
+
+func (s *scope) contains(line, column int) bool {
+	if s.outer == nil {
+		return true
+	}
+	after := line > s.start.Line || (line == s.start.Line && column > s.start.Column)
+	before := line < s.end.Line || (line == s.end.Line && column <= s.end.Column)
+	return after && before
+}
+
+type resolution struct {
+	// bindings 按声明顺序排列，scopes 中外层作用域在前
+	bindings []*Binding
+	scopes   []*scope
+
+	// refs 记录标识符引用的绑定，内置函数和宿主注入的变量不在其中；decls 记录声明的标识符
+	refs  map[*ast.Identifier]*Binding
+	decls map[*ast.Identifier]*Binding
+
+	// idents 是所有作为引用出现的标识符，包括没有绑定的
+	idents []*ast.Identifier
+}
+
+// resolve 计算绑定和引用。函数可以引用之后才声明的变量，所以先声明整个作用域再解析引用。
+func resolve(program *ast.Program) *resolution {
+	r := &resolution{
+		refs:  make(map[*ast.Identifier]*Binding),
+		decls: make(map[*ast.Identifier]*Binding),
+	}
+	s := r.newScope(nil, nil)
+	r.declareLets(s, program)
+	r.walk(s, program)
+	return r
+}
+
+func (r *resolution) newScope(outer *scope, block *ast.BlockStatement) *scope {
+	s := &scope{outer: outer, bindings: make(map[string]*Binding)}
+	if block != nil {
+		s.start, s.end = block.Token, block.End
+	}
+	r.scopes = append(r.scopes, s)
+	return s
+}
+
+func (r *resolution) declare(s *scope, ident *ast.Identifier, kind BindingKind) *Binding {
+	if b, ok := s.bindings[ident.Value]; ok {
+		b.assigns++
+		r.decls[ident] = b
+		return b
+	}
+
+	b := &Binding{
+		Name:    ident.Value,
+		Kind:    kind,
+		Decl:    ident,
+		assigns: 1,
+		shadows: s.outer.lookup(ident.Value),
+	}
+	s.bindings[ident.Value] = b
+	r.bindings = append(r.bindings, b)
+	r.decls[ident] = b
+	return b
+}
+
+// declareLets 声明 node 中属于作用域 s 的 let，不进入函数体和 catch 块
+func (r *resolution) declareLets(s *scope, node ast.Node) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FunctionLiteral:
+			return false
+		case *ast.TryExpression:
+			r.declareLets(s, n.Block)
+			if n.Finally != nil {
+				r.declareLets(s, n.Finally)
+			}
+			return false
+		case *ast.ExportStatement:
+			b := r.declare(s, n.Statement.Name, LetBinding)
+			b.exported = true
+			r.declareValue(b, n.Statement)
+			return false
+		case *ast.LetStatement:
+			r.declareValue(r.declare(s, n.Name, LetBinding), n)
+		}
+		return true
+	})
+}
+
+func (r *resolution) declareValue(b *Binding, ls *ast.LetStatement) {
+	if b.assigns == 1 {
+		b.Value = ls.Value
+	}
+	if fn, ok := ls.Value.(*ast.FunctionLiteral); ok && b.assigns == 1 {
+		b.fn = fn
+	} else {
+		b.fn = nil
+	}
+}
+
+func (r *resolution) walk(s *scope, node ast.Node) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.LetStatement:
+			// let 左边的名字不是引用
+			r.walk(s, n.Value)
+			return false
+		case *ast.MemberExpression:
+			r.walk(s, n.Object)
+			return false
+		case *ast.FunctionLiteral:
+			fs := r.newScope(s, n.Body)
+			for _, p := range n.Parameters {
+				r.declare(fs, p, ParamBinding)
+			}
+			r.declareLets(fs, n.Body)
+			r.walk(fs, n.Body)
+			return false
+		case *ast.TryExpression:
+			r.walk(s, n.Block)
+			if n.Catch != nil {
+				cs := r.newScope(s, n.Catch)
+				if n.Param != nil {
+					r.declare(cs, n.Param, CatchBinding)
+				}
+				r.declareLets(cs, n.Catch)
+				r.walk(cs, n.Catch)
+			}
+			if n.Finally != nil {
+				r.walk(s, n.Finally)
+			}
+			return false
+		case *ast.Identifier:
+			r.idents = append(r.idents, n)
+			if b := s.lookup(n.Value); b != nil {
+				b.used = true
+				r.refs[n] = b
+			}
+		}
+		return true
+	})
+}
+
+// Scopes 是程序的作用域信息，供编辑器等工具查询
+type Scopes struct {
+	r *resolution
+}
+
+func Resolve(program *ast.Program) *Scopes {
+	return &Scopes{r: resolve(program)}
+}
+
+// Lookup 返回标识符引用或声明的绑定，内置函数和未声明的名字返回 nil
+func (s *Scopes) Lookup(ident *ast.Identifier) *Binding {
+	if b, ok := s.r.refs[ident]; ok {
+		return b
+	}
+	return s.r.decls[ident]
+}
+
+// IdentifierAt 返回覆盖该位置的标识符，位置紧跟在标识符之后也算
+func (s *Scopes) IdentifierAt(line, column int) *ast.Identifier {
+	covers := func(ident *ast.Identifier) bool {
+		tok := ident.Token
+		return tok.Line == line && column >= tok.Column && column <= tok.Column+len(tok.Literal)
+	}
+
+	for _, ident := range s.r.idents {
+		if covers(ident) {
+			return ident
+		}
+	}
+	for ident := range s.r.decls {
+		if covers(ident) {
+			return ident
+		}
+	}
+	return nil
+}
+
+// NamesAt 返回在该位置可见的绑定，按名字排序，内层作用域的绑定遮蔽外层的同名绑定
+func (s *Scopes) NamesAt(line, column int) []*Binding {
+	var inner *scope
+	for _, sc := range s.r.scopes {
+		if sc.contains(line, column) && (inner == nil || depth(sc) > depth(inner)) {
+			inner = sc
+		}
+	}
+
+	seen := make(map[string]bool)
+	var bindings []*Binding
+	for sc := inner; sc != nil; sc = sc.outer {
+		for name, b := range sc.bindings {
+			if !seen[name] {
+				seen[name] = true
+				bindings = append(bindings, b)
+			}
+		}
+	}
+	sort.Slice(bindings, func(i, j int) bool {
+		return bindings[i].Name < bindings[j].Name
+	})
+	return bindings
+}
+
+func depth(s *scope) int {
+	d := 0
+	for ; s.outer != nil; s = s.outer {
+		d++
+	}
+	return d
+}
@@ -0,0 +1,21 @@
+package lint
+
+import (
+	"shanyl2400/go_compiler/ast"
+	"shanyl2400/go_compiler/token"
+)
+
+// expressionToken 返回表达式最左边的 token，中缀、调用和下标表达式的 Token 是运算符
+func expressionToken(e ast.Expression) token.Token {
+	switch e := e.(type) {
+	case *ast.InfixExpression:
+		return expressionToken(e.Left)
+	case *ast.CallExpression:
+		return expressionToken(e.Function)
+	case *ast.IndexExpression:
+		return expressionToken(e.Left)
+	case *ast.MemberExpression:
+		return expressionToken(e.Object)
+	}
+	return ast.TokenOf(e)
+}
@@ -0,0 +1,354 @@
+// Package lsp 实现通过标准输入输出通信的 Language Server Protocol 服务器
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"shanyl2400/go_compiler/ast"
+	"shanyl2400/go_compiler/evaluator"
+	"shanyl2400/go_compiler/format"
+	"shanyl2400/go_compiler/internal/framing"
+	"shanyl2400/go_compiler/lexer"
+	"shanyl2400/go_compiler/lint"
+	"shanyl2400/go_compiler/parser"
+	"shanyl2400/go_compiler/token"
+	"strings"
+)
+
+// Server 保存打开的文档。位置中的列按字节计算，源码只包含 ASCII 时与 LSP 的 UTF-16 列一致。
+type Server struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	docs     map[string]*document
+	shutdown bool
+
+	// err 是发送通知时的写入错误，Run 发现后退出
+	err error
+}
+
+type document struct {
+	text    string
+	program *ast.Program
+	errors  []*parser.Error
+	scopes  *lint.Scopes
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:   bufio.NewReader(in),
+		out:  out,
+		docs: make(map[string]*document),
+	}
+}
+
+// Serve 处理请求直到收到 exit 通知或输入结束
+func Serve(in io.Reader, out io.Writer) error {
+	return NewServer(in, out).Run()
+}
+
+func (s *Server) Run() error {
+	for {
+		body, err := framing.Read(s.in)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			if err := s.reply(nil, nil, &responseError{Code: codeParseError, Message: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+		if req.Method == "exit" {
+			return nil
+		}
+
+		result, rerr := s.handle(&req)
+		if s.err != nil {
+			return s.err
+		}
+		// 没有 id 的是通知，不需要回复
+		if req.ID == nil {
+			continue
+		}
+		if err := s.reply(req.ID, result, rerr); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) reply(id json.RawMessage, result any, rerr *responseError) error {
+	resp := response{JSONRPC: "2.0", ID: id, Error: rerr}
+	if id == nil {
+		resp.ID = json.RawMessage("null")
+	}
+	if rerr == nil {
+		raw, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		resp.Result = raw
+	}
+	return framing.Write(s.out, resp)
+}
+
+func (s *Server) notify(method string, params any) {
+	if s.err == nil {
+		s.err = framing.Write(s.out, notification{JSONRPC: "2.0", Method: method, Params: params})
+	}
+}
+
+func (s *Server) handle(req *request) (any, *responseError) {
+	if s.shutdown && req.ID != nil {
+		return nil, &responseError{Code: codeInvalidRequest, Message: "server is shut down"}
+	}
+
+	switch req.Method {
+	case "initialize":
+		return InitializeResult{
+			Capabilities: ServerCapabilities{
+				TextDocumentSync:           TextDocumentSyncKindFull,
+				DefinitionProvider:         true,
+				HoverProvider:              true,
+				CompletionProvider:         &CompletionOptions{},
+				DocumentFormattingProvider: true,
+			},
+			ServerInfo: ServerInfo{Name: "monkey-lsp"},
+		}, nil
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		s.update(params.TextDocument.URI, params.TextDocument.Text)
+		return nil, nil
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		if n := len(params.ContentChanges); n > 0 {
+			s.update(params.TextDocument.URI, params.ContentChanges[n-1].Text)
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		delete(s.docs, params.TextDocument.URI)
+		s.publish(params.TextDocument.URI, []Diagnostic{})
+		return nil, nil
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		return s.definition(params), nil
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		return s.hover(params), nil
+	case "textDocument/completion":
+		var params TextDocumentPositionParams
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		return s.completion(params), nil
+	case "textDocument/formatting":
+		var params DocumentFormattingParams
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		return s.formatting(params), nil
+	}
+
+	if req.ID == nil {
+		// 不认识的通知直接忽略
+		return nil, nil
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: "method not found: " + req.Method}
+}
+
+func unmarshalParams(req *request, v any) *responseError {
+	if err := json.Unmarshal(req.Params, v); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// update 重新解析文档并发布诊断信息
+func (s *Server) update(uri, text string) {
+	p := parser.New(lexer.New(text))
+	doc := &document{text: text, program: p.ParseProgram(), errors: p.ErrorList()}
+	s.docs[uri] = doc
+
+	diagnostics := []Diagnostic{}
+	for _, err := range doc.errors {
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    tokenRange(err.Token),
+			Severity: SeverityError,
+			Source:   "monkey",
+			Message:  err.Message,
+		})
+	}
+	// 有语法错误时语法树不完整，不做其他检查
+	if len(doc.errors) == 0 {
+		doc.scopes = lint.Resolve(doc.program)
+		for _, d := range lint.Run(doc.program, lint.DefaultRules...) {
+			pos := Position{Line: d.Line - 1, Character: d.Column - 1}
+			diagnostics = append(diagnostics, Diagnostic{
+				Range:    Range{Start: pos, End: pos},
+				Severity: SeverityWarning,
+				Code:     d.Rule,
+				Source:   "monkey-lint",
+				Message:  d.Message,
+			})
+		}
+	}
+
+	s.publish(uri, diagnostics)
+}
+
+func (s *Server) publish(uri string, diagnostics []Diagnostic) {
+	s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: diagnostics,
+	})
+}
+
+// identifierAt 返回光标处的标识符，文档有语法错误时返回 nil
+func (s *Server) identifierAt(params TextDocumentPositionParams) (*document, *ast.Identifier) {
+	doc, ok := s.docs[params.TextDocument.URI]
+	if !ok || doc.scopes == nil {
+		return nil, nil
+	}
+	ident := doc.scopes.IdentifierAt(params.Position.Line+1, params.Position.Character+1)
+	return doc, ident
+}
+
+func (s *Server) definition(params TextDocumentPositionParams) *Location {
+	doc, ident := s.identifierAt(params)
+	if ident == nil {
+		return nil
+	}
+	b := doc.scopes.Lookup(ident)
+	if b == nil {
+		return nil
+	}
+	return &Location{URI: params.TextDocument.URI, Range: tokenRange(b.Decl.Token)}
+}
+
+func (s *Server) hover(params TextDocumentPositionParams) *Hover {
+	doc, ident := s.identifierAt(params)
+	if ident == nil {
+		return nil
+	}
+
+	var text string
+	if b := doc.scopes.Lookup(ident); b != nil {
+		text = describe(b)
+	} else if isBuiltin(ident.Value) {
+		text = "builtin " + ident.Value
+	} else {
+		return nil
+	}
+
+	r := tokenRange(ident.Token)
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: "```monkey\n" + text + "\n```"},
+		Range:    &r,
+	}
+}
+
+// describe 返回绑定的简短说明，函数只显示参数
+func describe(b *lint.Binding) string {
+	if b.Kind != lint.LetBinding {
+		return b.Kind.String() + " " + b.Name
+	}
+
+	switch v := b.Value.(type) {
+	case *ast.FunctionLiteral:
+		params := make([]string, len(v.Parameters))
+		for i, p := range v.Parameters {
+			params[i] = p.Value
+		}
+		return fmt.Sprintf("let %s = fn(%s)", b.Name, strings.Join(params, ", "))
+	case nil:
+		return "let " + b.Name
+	default:
+		value := v.String()
+		if len(value) > 60 {
+			return "let " + b.Name
+		}
+		return "let " + b.Name + " = " + value
+	}
+}
+
+func (s *Server) completion(params TextDocumentPositionParams) []CompletionItem {
+	items := []CompletionItem{}
+
+	doc, ok := s.docs[params.TextDocument.URI]
+	if ok && doc.scopes != nil {
+		for _, b := range doc.scopes.NamesAt(params.Position.Line+1, params.Position.Character+1) {
+			kind := CompletionItemKindVariable
+			if _, ok := b.Value.(*ast.FunctionLiteral); ok {
+				kind = CompletionItemKindFunction
+			}
+			items = append(items, CompletionItem{Label: b.Name, Kind: kind, Detail: describe(b)})
+		}
+	}
+	for _, name := range evaluator.BuiltinNames() {
+		items = append(items, CompletionItem{Label: name, Kind: CompletionItemKindFunction, Detail: "builtin"})
+	}
+	return items
+}
+
+// formatting 用格式化后的源码替换整个文档，有语法错误或已经格式化时不修改
+func (s *Server) formatting(params DocumentFormattingParams) []TextEdit {
+	edits := []TextEdit{}
+
+	doc, ok := s.docs[params.TextDocument.URI]
+	if !ok || len(doc.errors) != 0 {
+		return edits
+	}
+	out := string(format.Program(doc.program, []byte(doc.text)))
+	if out == doc.text {
+		return edits
+	}
+
+	lines := strings.Split(doc.text, "\n")
+	end := Position{Line: len(lines) - 1, Character: len(lines[len(lines)-1])}
+	return append(edits, TextEdit{Range: Range{End: end}, NewText: out})
+}
+
+func isBuiltin(name string) bool {
+	for _, builtin := range evaluator.BuiltinNames() {
+		if builtin == name {
+			return true
+		}
+	}
+	return false
+}
+
+func tokenRange(tok token.Token) Range {
+	start := Position{Line: tok.Line - 1, Character: tok.Column - 1}
+	end := start
+	end.Character += len(tok.Literal)
+	return Range{Start: start, End: end}
+}
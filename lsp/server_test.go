@@ -0,0 +1,181 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"shanyl2400/go_compiler/internal/framing"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const uri = "file:///test.mk"
+
+type session struct {
+	in bytes.Buffer
+	id int
+}
+
+func (s *session) request(method string, params any) int {
+	s.id++
+	s.write(map[string]any{"jsonrpc": "2.0", "id": s.id, "method": method, "params": params})
+	return s.id
+}
+
+func (s *session) notify(method string, params any) {
+	s.write(map[string]any{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+func (s *session) write(v any) {
+	if err := framing.Write(&s.in, v); err != nil {
+		panic(err)
+	}
+}
+
+type received struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *responseError  `json:"error"`
+}
+
+// run 执行整个会话，返回按 id 索引的回复和按顺序排列的通知
+func (s *session) run(t *testing.T) (map[int]received, []received) {
+	var out bytes.Buffer
+	require.NoError(t, Serve(&s.in, &out))
+
+	responses := make(map[int]received)
+	var notifications []received
+	r := bufio.NewReader(&out)
+	for {
+		body, err := framing.Read(r)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		var msg received
+		require.NoError(t, json.Unmarshal(body, &msg))
+		if msg.ID != nil {
+			responses[*msg.ID] = msg
+		} else {
+			notifications = append(notifications, msg)
+		}
+	}
+	return responses, notifications
+}
+
+func position(uri string, line, character int) map[string]any {
+	return map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"position":     map[string]any{"line": line, "character": character},
+	}
+}
+
+func TestSession(t *testing.T) {
+	src := "let add = fn(a, b) {\n  a + b\n};\nlet x = add(1, 2);\nputs(x);\n"
+
+	var s session
+	initID := s.request("initialize", map[string]any{})
+	s.notify("initialized", map[string]any{})
+	s.notify("textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{"uri": uri, "languageId": "monkey", "version": 1, "text": src},
+	})
+	defID := s.request("textDocument/definition", position(uri, 3, 8))
+	paramDefID := s.request("textDocument/definition", position(uri, 1, 2))
+	hoverID := s.request("textDocument/hover", position(uri, 3, 9))
+	builtinHoverID := s.request("textDocument/hover", position(uri, 4, 1))
+	completionID := s.request("textDocument/completion", position(uri, 1, 2))
+	formatID := s.request("textDocument/formatting", map[string]any{"textDocument": map[string]any{"uri": uri}})
+	unknownID := s.request("textDocument/unknown", map[string]any{})
+	s.notify("textDocument/didChange", map[string]any{
+		"textDocument":   map[string]any{"uri": uri, "version": 2},
+		"contentChanges": []map[string]any{{"text": "let y = ;"}},
+	})
+	shutdownID := s.request("shutdown", nil)
+	s.notify("exit", nil)
+
+	responses, notifications := s.run(t)
+
+	var init InitializeResult
+	require.NoError(t, json.Unmarshal(responses[initID].Result, &init))
+	assert.True(t, init.Capabilities.DefinitionProvider)
+	assert.True(t, init.Capabilities.DocumentFormattingProvider)
+
+	var loc Location
+	require.NoError(t, json.Unmarshal(responses[defID].Result, &loc))
+	assert.Equal(t, Location{URI: uri, Range: Range{Start: Position{0, 4}, End: Position{0, 7}}}, loc)
+
+	require.NoError(t, json.Unmarshal(responses[paramDefID].Result, &loc))
+	assert.Equal(t, Range{Start: Position{0, 13}, End: Position{0, 14}}, loc.Range)
+
+	var hover Hover
+	require.NoError(t, json.Unmarshal(responses[hoverID].Result, &hover))
+	assert.Equal(t, "```monkey\nlet add = fn(a, b)\n```", hover.Contents.Value)
+
+	require.NoError(t, json.Unmarshal(responses[builtinHoverID].Result, &hover))
+	assert.Equal(t, "```monkey\nbuiltin puts\n```", hover.Contents.Value)
+
+	var items []CompletionItem
+	require.NoError(t, json.Unmarshal(responses[completionID].Result, &items))
+	labels := make([]string, len(items))
+	for i, item := range items {
+		labels[i] = item.Label
+	}
+	assert.Subset(t, labels, []string{"a", "b", "add", "x", "len", "puts"})
+
+	var edits []TextEdit
+	require.NoError(t, json.Unmarshal(responses[formatID].Result, &edits))
+	require.Len(t, edits, 1)
+	assert.Equal(t, "let add = fn(a, b) {\n    a + b;\n};\nlet x = add(1, 2);\nputs(x);\n", edits[0].NewText)
+	assert.Equal(t, Range{End: Position{5, 0}}, edits[0].Range)
+
+	require.NotNil(t, responses[unknownID].Error)
+	assert.Equal(t, codeMethodNotFound, responses[unknownID].Error.Code)
+	assert.Nil(t, responses[shutdownID].Error)
+
+	// didOpen 和 didChange 各发布一次诊断
+	require.Len(t, notifications, 2)
+	var diags PublishDiagnosticsParams
+	require.NoError(t, json.Unmarshal(notifications[0].Params, &diags))
+	assert.Empty(t, diags.Diagnostics)
+
+	require.NoError(t, json.Unmarshal(notifications[1].Params, &diags))
+	require.NotEmpty(t, diags.Diagnostics)
+	assert.Equal(t, SeverityError, diags.Diagnostics[0].Severity)
+	assert.Equal(t, "no prefix parse function for ; found", diags.Diagnostics[0].Message)
+	assert.Equal(t, Position{0, 8}, diags.Diagnostics[0].Range.Start)
+}
+
+func TestLintDiagnostics(t *testing.T) {
+	var s session
+	s.notify("textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{"uri": uri, "text": "let unused = 1;"},
+	})
+	s.notify("exit", nil)
+
+	_, notifications := s.run(t)
+	require.Len(t, notifications, 1)
+
+	var diags PublishDiagnosticsParams
+	require.NoError(t, json.Unmarshal(notifications[0].Params, &diags))
+	require.Len(t, diags.Diagnostics, 1)
+	assert.Equal(t, Diagnostic{
+		Range:    Range{Start: Position{0, 4}, End: Position{0, 4}},
+		Severity: SeverityWarning,
+		Code:     "unused",
+		Source:   "monkey-lint",
+		Message:  "unused declared and not used",
+	}, diags.Diagnostics[0])
+}
+
+func TestInvalidContentLength(t *testing.T) {
+	var out bytes.Buffer
+	err := Serve(strings.NewReader("Content-Length: -1\r\n\r\n"), &out)
+	assert.EqualError(t, err, `invalid Content-Length: "-1"`)
+}
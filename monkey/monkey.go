@@ -0,0 +1,206 @@
+// Package monkey 提供给 Go 宿主程序嵌入解释器的接口
+package monkey
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"shanyl2400/go_compiler/evaluator"
+	"shanyl2400/go_compiler/lexer"
+	"shanyl2400/go_compiler/object"
+	"shanyl2400/go_compiler/parser"
+	"strings"
+)
+
+// ParseError 源码无法解析时返回
+type ParseError struct {
+	Errors []string
+}
+
+func (e *ParseError) Error() string {
+	return "parser errors: " + strings.Join(e.Errors, "; ")
+}
+
+// RuntimeError 脚本执行出错时返回
+type RuntimeError struct {
+	Err *object.Error
+}
+
+func (e *RuntimeError) Error() string {
+	return e.Err.Message
+}
+
+// Unwrap 返回触发错误的 Go 错误，可以用 errors.Is 判断是否超出执行限制
+func (e *RuntimeError) Unwrap() error {
+	return e.Err.Err
+}
+
+// PanicError 执行中发生 panic 时返回，避免脚本或解释器的问题使宿主程序崩溃
+type PanicError struct {
+	Value any
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// recoverPanic 在 Run 和 Call 中 defer 调用，把 panic 转换为 PanicError
+func recoverPanic(obj *object.Object, err *error) {
+	if r := recover(); r != nil {
+		*obj, *err = nil, &PanicError{Value: r}
+	}
+}
+
+type Option func(*Interpreter)
+
+func WithStdout(w io.Writer) Option {
+	return func(i *Interpreter) {
+		i.ctx.Stdout = w
+	}
+}
+
+func WithStderr(w io.Writer) Option {
+	return func(i *Interpreter) {
+		i.ctx.Stderr = w
+	}
+}
+
+// WithLimits 限制每次 Run 或 Call 的执行步数、调用深度和时间
+func WithLimits(limits evaluator.Limits) Option {
+	return func(i *Interpreter) {
+		i.limits = limits
+	}
+}
+
+// WithSearchPath 设置 import 查找模块的目录，模块只能位于这些目录内。
+// 没有设置时脚本不能使用 import
+func WithSearchPath(dirs ...string) Option {
+	return func(i *Interpreter) {
+		i.searchPath = dirs
+	}
+}
+
+// Interpreter 持有全局环境，多次 Run 之间共享变量和已加载的模块
+type Interpreter struct {
+	ctx        *object.Context
+	env        *object.Environment
+	eval       *evaluator.Evaluator
+	limits     evaluator.Limits
+	searchPath []string
+}
+
+func New(opts ...Option) *Interpreter {
+	i := &Interpreter{
+		ctx: object.NewContext(os.Stdout, os.Stderr),
+		env: object.NewEnvironment(),
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	i.eval = evaluator.New(i.ctx)
+	i.eval.SetLimits(i.limits)
+	if len(i.searchPath) == 0 {
+		i.eval.DisableImports()
+	} else {
+		i.eval.SetSearchPath(i.searchPath...)
+	}
+	return i
+}
+
+// RegisterBuiltin 为当前解释器注册内置函数，不影响其他实例
+func (i *Interpreter) RegisterBuiltin(name string, fn object.BuiltinFunction) {
+	i.eval.RegisterBuiltin(name, fn)
+}
+
+// RegisterFunc 把普通的 Go 函数注册为内置函数，参数和返回值的转换规则见 object.WrapFunc
+func (i *Interpreter) RegisterFunc(name string, fn any) error {
+	builtin, err := object.WrapFunc(name, fn)
+	if err != nil {
+		return err
+	}
+	i.eval.RegisterBuiltin(name, builtin)
+	return nil
+}
+
+// Run 解析并执行 src，返回最后一条语句的值
+func (i *Interpreter) Run(src string) (object.Object, error) {
+	return i.RunContext(context.Background(), src)
+}
+
+// RunContext 与 Run 相同，ctx 取消时中止执行
+func (i *Interpreter) RunContext(ctx context.Context, src string) (obj object.Object, err error) {
+	defer recoverPanic(&obj, &err)
+
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, &ParseError{Errors: p.Errors()}
+	}
+
+	return result(i.eval.EvalContext(ctx, program, i.env))
+}
+
+// Set 把 Go 值转换为对象后绑定到全局环境
+func (i *Interpreter) Set(name string, v any) error {
+	obj, err := object.FromGo(v)
+	if err != nil {
+		return err
+	}
+	i.env.Set(name, obj)
+	return nil
+}
+
+// SetHost 把 Go 值包装为宿主对象绑定到全局环境，脚本只能访问 members 中列出的导出字段和方法
+func (i *Interpreter) SetHost(name string, v any, members ...string) {
+	i.env.Set(name, object.NewHostObject(v, members...))
+}
+
+// Get 读取全局变量并转换为 Go 值，无法转换的对象（如函数）原样返回
+func (i *Interpreter) Get(name string) (any, bool) {
+	obj, ok := i.env.Get(name)
+	if !ok {
+		return nil, false
+	}
+	v, err := object.ToGo(obj)
+	if err != nil {
+		return obj, true
+	}
+	return v, true
+}
+
+// Call 调用脚本中定义的函数，参数会自动转换为对象
+func (i *Interpreter) Call(fnName string, args ...any) (object.Object, error) {
+	return i.CallContext(context.Background(), fnName, args...)
+}
+
+// CallContext 与 Call 相同，ctx 取消时中止执行
+func (i *Interpreter) CallContext(ctx context.Context, fnName string, args ...any) (obj object.Object, err error) {
+	defer recoverPanic(&obj, &err)
+
+	fn, ok := i.env.Get(fnName)
+	if !ok {
+		return nil, fmt.Errorf("function not found: %s", fnName)
+	}
+
+	objs := make([]object.Object, len(args))
+	for idx, arg := range args {
+		converted, err := object.FromGo(arg)
+		if err != nil {
+			return nil, err
+		}
+		objs[idx] = converted
+	}
+
+	return result(i.eval.ApplyContext(ctx, fn, objs...))
+}
+
+func result(obj object.Object) (object.Object, error) {
+	if errObj, ok := obj.(*object.Error); ok {
+		return nil, &RuntimeError{Err: errObj}
+	}
+	if obj == nil {
+		return evaluator.NULL, nil
+	}
+	return obj, nil
+}
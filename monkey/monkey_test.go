@@ -0,0 +1,338 @@
+package monkey
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"shanyl2400/go_compiler/evaluator"
+	"shanyl2400/go_compiler/object"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	i := New()
+
+	result, err := i.Run("let add = fn(a, b) { a + b }; add(1, 2)")
+	assert.NoError(t, err)
+	assert.Equal(t, "3", result.Inspect())
+
+	// 全局环境在多次 Run 之间共享
+	result, err = i.Run("add(3, 4)")
+	assert.NoError(t, err)
+	assert.Equal(t, "7", result.Inspect())
+}
+
+func TestRunErrors(t *testing.T) {
+	i := New()
+
+	_, err := i.Run("let = 5")
+	assert.IsType(t, &ParseError{}, err)
+
+	_, err = i.Run("1 + true")
+	assert.IsType(t, &RuntimeError{}, err)
+	assert.Equal(t, "type mismatch: INTEGER + BOOLEAN", err.Error())
+}
+
+func TestRunDoesNotPanic(t *testing.T) {
+	i := New()
+
+	_, err := i.Run("let add = fn(a, b) { a + b }; add(1)")
+	assert.IsType(t, &RuntimeError{}, err)
+	assert.Equal(t, "wrong number of arguments to add. got=1, want=2", err.Error())
+
+	_, err = i.Call("add", 1)
+	assert.IsType(t, &RuntimeError{}, err)
+	assert.Equal(t, "wrong number of arguments to add. got=1, want=2", err.Error())
+
+	_, err = i.Run("1 / 0")
+	assert.IsType(t, &RuntimeError{}, err)
+	assert.Equal(t, "division by zero", err.Error())
+
+	// 宿主注册的函数 panic 时返回错误，解释器仍然可以继续使用
+	i.RegisterBuiltin("boom", func(ctx *object.Context, args ...object.Object) object.Object {
+		panic("boom")
+	})
+	_, err = i.Run("boom()")
+	assert.IsType(t, &PanicError{}, err)
+	assert.Equal(t, "panic: boom", err.Error())
+	_, err = i.Run("let f = fn() { boom() }")
+	assert.NoError(t, err)
+	_, err = i.Call("f")
+	assert.IsType(t, &PanicError{}, err)
+
+	result, err := i.Run("add(1, 2)")
+	assert.NoError(t, err)
+	assert.Equal(t, "3", result.Inspect())
+}
+
+func TestSetGet(t *testing.T) {
+	i := New()
+
+	assert.NoError(t, i.Set("config", map[string]any{
+		"name":  "svc",
+		"ports": []any{80, 443},
+		"debug": true,
+	}))
+	_, err := i.Run(`let port = config.ports[1]; let name = config.name + "-1"`)
+	assert.NoError(t, err)
+
+	port, ok := i.Get("port")
+	assert.True(t, ok)
+	assert.Equal(t, int64(443), port)
+
+	name, _ := i.Get("name")
+	assert.Equal(t, "svc-1", name)
+
+	config, _ := i.Get("config")
+	assert.Equal(t, map[string]any{
+		"name":  "svc",
+		"ports": []any{int64(80), int64(443)},
+		"debug": true,
+	}, config)
+
+	_, ok = i.Get("missing")
+	assert.False(t, ok)
+
+	assert.Error(t, i.Set("ch", make(chan int)))
+}
+
+func TestCall(t *testing.T) {
+	var out bytes.Buffer
+	i := New(WithStdout(&out))
+
+	_, err := i.Run(`let greet = fn(name) { puts("hi " + name); len(name) }`)
+	assert.NoError(t, err)
+
+	result, err := i.Call("greet", "bob")
+	assert.NoError(t, err)
+	assert.Equal(t, "3", result.Inspect())
+	assert.Equal(t, "hi bob\n", out.String())
+
+	_, err = i.Call("missing")
+	assert.Error(t, err)
+
+	_, err = i.Call("greet", 1)
+	assert.IsType(t, &RuntimeError{}, err)
+}
+
+func TestRegisterBuiltin(t *testing.T) {
+	i := New()
+	i.RegisterBuiltin("double", func(ctx *object.Context, args ...object.Object) object.Object {
+		n := args[0].(*object.Integer)
+		return &object.Integer{Value: n.Value * 2}
+	})
+
+	result, err := i.Run("double(21)")
+	assert.NoError(t, err)
+	assert.Equal(t, "42", result.Inspect())
+
+	// 注册的函数只属于当前解释器
+	_, err = New().Run("double(21)")
+	assert.EqualError(t, err, "identifier not found: double")
+}
+
+func TestRegisterFunc(t *testing.T) {
+	i := New()
+	assert.NoError(t, i.RegisterFunc("repeat", strings.Repeat))
+	assert.NoError(t, i.RegisterFunc("sum", func(nums ...int32) int32 {
+		var total int32
+		for _, n := range nums {
+			total += n
+		}
+		return total
+	}))
+	assert.NoError(t, i.RegisterFunc("lookup", func(m map[string]int, key string) (int, error) {
+		v, ok := m[key]
+		if !ok {
+			return 0, fmt.Errorf("no key %q", key)
+		}
+		return v, nil
+	}))
+	assert.Error(t, i.RegisterFunc("notfn", 1))
+
+	tests := []struct {
+		input    string
+		expected string
+		err      string
+	}{
+		{`repeat("ab", 3)`, "ababab", ""},
+		{`sum()`, "0", ""},
+		{`sum(1, 2, 3)`, "6", ""},
+		{`lookup({"a": 1}, "a")`, "1", ""},
+		{`lookup({"a": 1}, "b")`, "", `no key "b"`},
+		{`repeat("ab")`, "", "wrong number of arguments. got=1, want=2"},
+		{`repeat(1, 2)`, "", "argument 1: cannot use INTEGER as string"},
+	}
+
+	for _, tt := range tests {
+		result, err := i.Run(tt.input)
+		if tt.err != "" {
+			assert.EqualError(t, err, tt.err, tt.input)
+			continue
+		}
+		assert.NoError(t, err, tt.input)
+		assert.Equal(t, tt.expected, result.Inspect(), tt.input)
+	}
+}
+
+func TestSetStructAndFloat(t *testing.T) {
+	type server struct {
+		Host  string  `monkey:"host"`
+		Ratio float64 `monkey:"ratio"`
+	}
+
+	i := New()
+	assert.NoError(t, i.Set("srv", server{Host: "db", Ratio: 0.5}))
+
+	result, err := i.Run(`srv.ratio`)
+	assert.NoError(t, err)
+	assert.Equal(t, "0.5", result.Inspect())
+
+	// 浮点数只用于和 Go 交换数据，不能和整数混合运算
+	_, err = i.Run(`srv.ratio * 3`)
+	assert.EqualError(t, err, "type mismatch: FLOAT * INTEGER")
+
+	srv, _ := i.Get("srv")
+	assert.Equal(t, map[string]any{"host": "db", "ratio": 0.5}, srv)
+}
+
+type testLogger struct {
+	Prefix string
+	Level  int
+	lines  []string
+}
+
+func (l *testLogger) Log(msg string) int {
+	l.lines = append(l.lines, l.Prefix+msg)
+	return len(l.lines)
+}
+
+func (l *testLogger) Reset() {
+	l.lines = nil
+}
+
+func TestSetHost(t *testing.T) {
+	logger := &testLogger{Prefix: "> ", Level: 2}
+	i := New()
+	i.SetHost("log", logger, "Prefix", "Log")
+
+	tests := []struct {
+		input    string
+		expected string
+		err      string
+	}{
+		{`log.Prefix`, "> ", ""},
+		{`log.Log("a"); log.Log("b")`, "2", ""},
+		{`log?.Log("c")`, "3", ""},
+		{`log.Level`, "", "member not accessible: host(*monkey.testLogger).Level"},
+		{`log.Reset()`, "", "member not accessible: host(*monkey.testLogger).Reset"},
+		{`log.lines`, "", "member not accessible: host(*monkey.testLogger).lines"},
+		{`log.Log(1)`, "", "argument 1: cannot use INTEGER as string"},
+	}
+
+	for _, tt := range tests {
+		result, err := i.Run(tt.input)
+		if tt.err != "" {
+			assert.EqualError(t, err, tt.err, tt.input)
+			continue
+		}
+		assert.NoError(t, err, tt.input)
+		assert.Equal(t, tt.expected, result.Inspect(), tt.input)
+	}
+	assert.Equal(t, []string{"> a", "> b", "> c"}, logger.lines)
+
+	// 未登记的导出成员同样不可访问
+	i.SetHost("bare", logger)
+	_, err := i.Run(`bare.Prefix`)
+	assert.Error(t, err)
+
+	v, _ := i.Get("log")
+	assert.Same(t, logger, v)
+}
+
+type testStore struct {
+	Items []string
+}
+
+func (s *testStore) Fail() {
+	panic("disk full")
+}
+
+func TestGoFuncPanicsAndMemory(t *testing.T) {
+	i := New(WithLimits(evaluator.Limits{MaxMemory: 1 << 16}))
+	assert.NoError(t, i.RegisterFunc("explode", func() int { panic("bad") }))
+	assert.NoError(t, i.RegisterFunc("names", func(n int) []string { return make([]string, n) }))
+	i.SetHost("store", &testStore{Items: make([]string, 10000)}, "Items", "Fail")
+
+	_, err := i.Run("explode()")
+	assert.EqualError(t, err, "explode panicked: bad")
+	_, err = i.Run("store.Fail()")
+	assert.EqualError(t, err, "host(*monkey.testStore).Fail panicked: disk full")
+
+	// Go 函数返回值和宿主字段转换得到的对象计入内存限制
+	result, err := i.Run("len(names(10))")
+	assert.NoError(t, err)
+	assert.Equal(t, "10", result.Inspect())
+	_, err = i.Run("names(10000)")
+	assert.ErrorIs(t, err, evaluator.ErrMemoryLimitExceeded)
+	_, err = i.Run("store.Items")
+	assert.ErrorIs(t, err, evaluator.ErrMemoryLimitExceeded)
+}
+
+func TestRunLimits(t *testing.T) {
+	i := New(WithLimits(evaluator.Limits{MaxSteps: 500}))
+
+	_, err := i.Run("while (true) { 1 }")
+	assert.ErrorIs(t, err, evaluator.ErrStepLimitExceeded)
+
+	// 每次 Run 重新计数
+	result, err := i.Run("1 + 1")
+	assert.NoError(t, err)
+	assert.Equal(t, "2", result.Inspect())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = New().RunContext(ctx, "while (true) { 1 }")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestSearchPath(t *testing.T) {
+	dir := t.TempDir()
+	src := `export let greet = fn(name) { "hello " + name };`
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "greet.mk"), []byte(src), 0o644))
+
+	i := New(WithSearchPath(dir))
+	_, err := i.Run(`let g = import "greet";`)
+	assert.NoError(t, err)
+
+	result, err := i.Run(`g.greet("monkey")`)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello monkey", result.Inspect())
+
+	// 没有设置搜索路径时不能 import
+	_, err = New().Run(`import "greet"`)
+	assert.EqualError(t, err, "import is disabled: greet")
+
+	// 模块只能位于搜索路径内
+	// 文件内容无法解析，如果被读取会返回解析错误
+	outside := filepath.Join(filepath.Dir(dir), filepath.Base(dir)+"-outside.mk")
+	assert.NoError(t, os.WriteFile(outside, []byte("root:x:0:0"), 0o644))
+	defer os.Remove(outside)
+	for _, src := range []string{
+		`import "` + outside + `"`,
+		`import "../` + filepath.Base(outside) + `"`,
+		`import "sub/../../` + filepath.Base(outside) + `"`,
+		`import "./../` + filepath.Base(outside) + `"`,
+	} {
+		_, err = i.Run(src)
+		if assert.Error(t, err, src) {
+			assert.NotContains(t, err.Error(), "parser errors", src)
+		}
+	}
+}
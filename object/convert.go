@@ -0,0 +1,386 @@
+package object
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// TagName 结构体字段通过 `monkey:"name"` 指定转换后的键名，`monkey:"-"` 表示忽略该字段
+const TagName = "monkey"
+
+var objectType = reflect.TypeOf((*Object)(nil)).Elem()
+
+// FromGo 把 Go 值转换为对象。
+// 支持整数、浮点数、字符串、布尔值、切片、数组、键为字符串的 map、结构体、指针和 nil；
+// 结构体转换为 Hash，键按字段声明顺序排列。
+// 值中有循环引用或无符号整数超出 INTEGER 的范围时返回错误。
+func FromGo(v any) (Object, error) {
+	return fromGo(reflect.ValueOf(v), make(map[ref]bool))
+}
+
+// ref 标识转换过程中正在访问的指针、map 或切片，再次遇到时说明值中有循环引用。
+// 同一地址可能是不同类型的值（如结构体和它的第一个字段），切片还要区分长度
+type ref struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// enter 记录正在访问的引用，返回的函数在访问结束后调用
+func enter(v reflect.Value, seen map[ref]bool) (func(), error) {
+	r := ref{ptr: v.Pointer(), typ: v.Type()}
+	if v.Kind() == reflect.Slice {
+		r.len = v.Len()
+	}
+	if seen[r] {
+		return nil, fmt.Errorf("cyclic value: %s", v.Type())
+	}
+	seen[r] = true
+	return func() { delete(seen, r) }, nil
+}
+
+func fromGo(v reflect.Value, seen map[ref]bool) (Object, error) {
+	if !v.IsValid() {
+		return NULL, nil
+	}
+	if v.Type().Implements(objectType) {
+		if v.IsNil() {
+			return NULL, nil
+		}
+		return v.Interface().(Object), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return TRUE, nil
+		}
+		return FALSE, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Integer{Value: v.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("value %d out of range for INTEGER", v.Uint())
+		}
+		return &Integer{Value: int64(v.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Float{Value: v.Float()}, nil
+	case reflect.String:
+		return &String{Value: v.String()}, nil
+	case reflect.Interface:
+		if v.IsNil() {
+			return NULL, nil
+		}
+		return fromGo(v.Elem(), seen)
+	case reflect.Pointer:
+		if v.IsNil() {
+			return NULL, nil
+		}
+		leave, err := enter(v, seen)
+		if err != nil {
+			return nil, err
+		}
+		defer leave()
+		return fromGo(v.Elem(), seen)
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice {
+			if v.IsNil() {
+				return NULL, nil
+			}
+			leave, err := enter(v, seen)
+			if err != nil {
+				return nil, err
+			}
+			defer leave()
+		}
+		elements := make([]Object, v.Len())
+		for i := range elements {
+			elem, err := fromGo(v.Index(i), seen)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = elem
+		}
+		return &Array{Elements: elements}, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return NULL, nil
+		}
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type: %s", v.Type().Key())
+		}
+		leave, err := enter(v, seen)
+		if err != nil {
+			return nil, err
+		}
+		defer leave()
+
+		keys := make([]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			keys = append(keys, key.String())
+		}
+		// map 遍历无序，按键排序保证 Hash 的顺序稳定
+		sort.Strings(keys)
+
+		hash := NewHash()
+		for _, key := range keys {
+			value, err := fromGo(v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key())), seen)
+			if err != nil {
+				return nil, err
+			}
+			hash.Set(&String{Value: key}, value)
+		}
+		return hash, nil
+	case reflect.Struct:
+		hash := NewHash()
+		for _, field := range structFields(v.Type()) {
+			value, err := fromGo(v.FieldByIndex(field.index), seen)
+			if err != nil {
+				return nil, err
+			}
+			hash.Set(&String{Value: field.name}, value)
+		}
+		return hash, nil
+	}
+	return nil, fmt.Errorf("unsupported go type: %s", v.Type())
+}
+
+// ToGo 把对象转换为 Go 值。
+// INTEGER 转为 int64，FLOAT 转为 float64，ARRAY 转为 []any，
+// 键全部为字符串的 HASH 转为 map[string]any，其他 HASH 转为 map[any]any。
+func ToGo(obj Object) (any, error) {
+	switch obj := obj.(type) {
+	case nil, *Null:
+		return nil, nil
+	case *Boolean:
+		return obj.Value, nil
+	case *Integer:
+		return obj.Value, nil
+	case *Float:
+		return obj.Value, nil
+	case *String:
+		return obj.Value, nil
+	case *Array:
+		elements := make([]any, len(obj.Elements))
+		for i, elem := range obj.Elements {
+			v, err := ToGo(elem)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = v
+		}
+		return elements, nil
+	case *Hash:
+		return hashToGo(obj)
+	case *HostObject:
+		return obj.Value, nil
+	}
+	return nil, fmt.Errorf("cannot convert %s to go value", obj.Type())
+}
+
+func hashToGo(hash *Hash) (any, error) {
+	stringKeys := true
+	for _, pair := range hash.Items() {
+		if _, ok := pair.Key.(*String); !ok {
+			stringKeys = false
+			break
+		}
+	}
+
+	if stringKeys {
+		m := make(map[string]any, hash.Len())
+		for _, pair := range hash.Items() {
+			v, err := ToGo(pair.Value)
+			if err != nil {
+				return nil, err
+			}
+			m[pair.Key.(*String).Value] = v
+		}
+		return m, nil
+	}
+
+	m := make(map[any]any, hash.Len())
+	for _, pair := range hash.Items() {
+		if pair.Key.Type() == ARRAY_OBJ {
+			return nil, fmt.Errorf("cannot convert ARRAY hash key to go value")
+		}
+		k, err := ToGo(pair.Key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := ToGo(pair.Value)
+		if err != nil {
+			return nil, err
+		}
+		m[k] = v
+	}
+	return m, nil
+}
+
+// ToGoValue 把对象转换为指定类型的 Go 值，结构体按字段名或 tag 从 HASH 中取值。
+// 数值超出目标类型的范围时返回错误，不会截断
+func ToGoValue(obj Object, typ reflect.Type) (reflect.Value, error) {
+	if typ.Implements(objectType) && reflect.TypeOf(obj).AssignableTo(typ) {
+		return reflect.ValueOf(obj), nil
+	}
+
+	out := reflect.New(typ).Elem()
+	if obj == NULL {
+		return out, nil
+	}
+	if host, ok := obj.(*HostObject); ok {
+		v := reflect.ValueOf(host.Value)
+		if v.IsValid() && v.Type().AssignableTo(typ) {
+			out.Set(v)
+			return out, nil
+		}
+	}
+
+	switch typ.Kind() {
+	case reflect.Interface:
+		v, err := ToGo(obj)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if v == nil {
+			return out, nil
+		}
+		if !reflect.TypeOf(v).AssignableTo(typ) {
+			break
+		}
+		out.Set(reflect.ValueOf(v))
+		return out, nil
+	case reflect.Bool:
+		if b, ok := obj.(*Boolean); ok {
+			out.SetBool(b.Value)
+			return out, nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i, ok := obj.(*Integer); ok {
+			if out.OverflowInt(i.Value) {
+				return reflect.Value{}, fmt.Errorf("value %d out of range for %s", i.Value, typ)
+			}
+			out.SetInt(i.Value)
+			return out, nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if i, ok := obj.(*Integer); ok {
+			if i.Value < 0 || out.OverflowUint(uint64(i.Value)) {
+				return reflect.Value{}, fmt.Errorf("value %d out of range for %s", i.Value, typ)
+			}
+			out.SetUint(uint64(i.Value))
+			return out, nil
+		}
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		switch n := obj.(type) {
+		case *Float:
+			f = n.Value
+		case *Integer:
+			f = float64(n.Value)
+		default:
+			return reflect.Value{}, fmt.Errorf("cannot use %s as %s", obj.Type(), typ)
+		}
+		if out.OverflowFloat(f) {
+			return reflect.Value{}, fmt.Errorf("value %v out of range for %s", f, typ)
+		}
+		out.SetFloat(f)
+		return out, nil
+	case reflect.String:
+		if s, ok := obj.(*String); ok {
+			out.SetString(s.Value)
+			return out, nil
+		}
+	case reflect.Pointer:
+		elem, err := ToGoValue(obj, typ.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		out.Set(reflect.New(typ.Elem()))
+		out.Elem().Set(elem)
+		return out, nil
+	case reflect.Slice:
+		arr, ok := obj.(*Array)
+		if !ok {
+			break
+		}
+		out.Set(reflect.MakeSlice(typ, len(arr.Elements), len(arr.Elements)))
+		for i, elem := range arr.Elements {
+			v, err := ToGoValue(elem, typ.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			out.Index(i).Set(v)
+		}
+		return out, nil
+	case reflect.Map:
+		hash, ok := obj.(*Hash)
+		if !ok {
+			break
+		}
+		out.Set(reflect.MakeMapWithSize(typ, hash.Len()))
+		for _, pair := range hash.Items() {
+			k, err := ToGoValue(pair.Key, typ.Key())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			v, err := ToGoValue(pair.Value, typ.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			out.SetMapIndex(k, v)
+		}
+		return out, nil
+	case reflect.Struct:
+		hash, ok := obj.(*Hash)
+		if !ok {
+			break
+		}
+		for _, field := range structFields(typ) {
+			pair, ok := hash.Get(&String{Value: field.name})
+			if !ok {
+				continue
+			}
+			v, err := ToGoValue(pair.Value, field.typ)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("field %s: %w", field.name, err)
+			}
+			out.FieldByIndex(field.index).Set(v)
+		}
+		return out, nil
+	}
+	return reflect.Value{}, fmt.Errorf("cannot use %s as %s", obj.Type(), typ)
+}
+
+type structField struct {
+	name  string
+	index []int
+	typ   reflect.Type
+}
+
+// structFields 返回参与转换的导出字段，键名优先取 monkey tag
+func structFields(typ reflect.Type) []structField {
+	fields := make([]structField, 0, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name := f.Name
+		if tag, ok := f.Tag.Lookup(TagName); ok {
+			tag, _, _ = strings.Cut(tag, ",")
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+		fields = append(fields, structField{name: name, index: f.Index, typ: f.Type})
+	}
+	return fields
+}
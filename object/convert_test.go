@@ -0,0 +1,210 @@
+package object
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type address struct {
+	City string `monkey:"city"`
+	Zip  int    `monkey:"zip,omitempty"`
+}
+
+type person struct {
+	Name    string   `monkey:"name"`
+	Age     int      `monkey:"age"`
+	Score   float64  `monkey:"score"`
+	Admin   bool     `monkey:"admin"`
+	Tags    []string `monkey:"tags"`
+	Home    *address `monkey:"home"`
+	Secret  string   `monkey:"-"`
+	private int
+}
+
+func TestFromGo(t *testing.T) {
+	tests := []struct {
+		input    any
+		expected string
+	}{
+		{nil, "null"},
+		{true, "true"},
+		{42, "42"},
+		{uint8(7), "7"},
+		{1.5, "1.5"},
+		{float32(0.25), "0.25"},
+		{"hi", "hi"},
+		{[]int{1, 2}, "[1, 2]"},
+		{[2]string{"a", "b"}, "[a, b]"},
+		{map[string]any{"b": 1, "a": []any{nil, "x"}}, "{a: [null, x], b: 1}"},
+		{(*address)(nil), "null"},
+		{
+			person{Name: "ann", Age: 30, Score: 9.5, Tags: []string{"x"}, Home: &address{City: "sh"}, Secret: "s"},
+			"{name: ann, age: 30, score: 9.5, admin: false, tags: [x], home: {city: sh, zip: 0}}",
+		},
+	}
+
+	for _, tt := range tests {
+		obj, err := FromGo(tt.input)
+		if err != nil {
+			t.Errorf("FromGo(%#v) returned error: %v", tt.input, err)
+			continue
+		}
+		if obj.Inspect() != tt.expected {
+			t.Errorf("FromGo(%#v) wrong. expected=%q, got=%q", tt.input, tt.expected, obj.Inspect())
+		}
+	}
+
+	if obj, _ := FromGo(nil); obj != NULL {
+		t.Errorf("FromGo(nil) is not the NULL singleton")
+	}
+	if obj, _ := FromGo(true); obj != TRUE {
+		t.Errorf("FromGo(true) is not the TRUE singleton")
+	}
+
+	for _, input := range []any{make(chan int), map[int]int{1: 1}, func() {}} {
+		if _, err := FromGo(input); err == nil {
+			t.Errorf("FromGo(%T) expected error", input)
+		}
+	}
+
+	type node struct{ Next *node }
+	cyclic := &node{}
+	cyclic.Next = cyclic
+	if _, err := FromGo(cyclic); err == nil {
+		t.Errorf("FromGo of cyclic value expected error")
+	}
+
+	m := map[string]any{}
+	m["self"] = m
+	list := []any{nil}
+	list[0] = list
+	nested := map[string]any{"list": []any{1, nil}}
+	nested["list"].([]any)[1] = nested
+	for _, input := range []any{m, list, nested} {
+		if _, err := FromGo(input); err == nil || !strings.Contains(err.Error(), "cyclic value") {
+			t.Errorf("FromGo of self-referencing %T expected cyclic error. got=%v", input, err)
+		}
+	}
+
+	// 同一个值出现多次但没有循环时可以转换
+	shared := []int{1}
+	if obj, err := FromGo([]any{shared, shared}); err != nil || obj.Inspect() != "[[1], [1]]" {
+		t.Errorf("FromGo of shared slice wrong. got=%v, %v", obj, err)
+	}
+
+	if _, err := FromGo(uint64(math.MaxUint64)); err == nil {
+		t.Errorf("FromGo(MaxUint64) expected range error")
+	}
+}
+
+func TestToGo(t *testing.T) {
+	hash := NewHash()
+	hash.Set(&Integer{Value: 1}, &String{Value: "one"})
+
+	tests := []struct {
+		input    Object
+		expected any
+	}{
+		{NULL, nil},
+		{FALSE, false},
+		{&Integer{Value: 3}, int64(3)},
+		{&Float{Value: 0.5}, 0.5},
+		{&String{Value: "s"}, "s"},
+		{&Array{Elements: []Object{&Integer{Value: 1}, NULL}}, []any{int64(1), nil}},
+		{hash, map[any]any{int64(1): "one"}},
+	}
+
+	for _, tt := range tests {
+		v, err := ToGo(tt.input)
+		if err != nil {
+			t.Errorf("ToGo(%s) returned error: %v", tt.input.Inspect(), err)
+			continue
+		}
+		if !reflect.DeepEqual(v, tt.expected) {
+			t.Errorf("ToGo(%s) wrong. expected=%#v, got=%#v", tt.input.Inspect(), tt.expected, v)
+		}
+	}
+
+	if _, err := ToGo(&Builtin{}); err == nil {
+		t.Errorf("ToGo(BUILTIN) expected error")
+	}
+}
+
+func TestGoRoundTrip(t *testing.T) {
+	values := []any{
+		nil,
+		true,
+		int64(-5),
+		2.75,
+		"text",
+		[]any{int64(1), "two", []any{3.5}},
+		map[string]any{"a": int64(1), "b": []any{"c", nil}, "d": map[string]any{"e": false}},
+	}
+
+	for _, v := range values {
+		obj, err := FromGo(v)
+		if err != nil {
+			t.Fatalf("FromGo(%#v) returned error: %v", v, err)
+		}
+		back, err := ToGo(obj)
+		if err != nil {
+			t.Fatalf("ToGo(%s) returned error: %v", obj.Inspect(), err)
+		}
+		if !reflect.DeepEqual(v, back) {
+			t.Errorf("round trip changed value. expected=%#v, got=%#v", v, back)
+		}
+	}
+
+	// 整数在各种类型的取值范围内可以来回转换，超出范围时返回错误
+	typed := []any{
+		int8(math.MinInt8), int16(math.MaxInt16), int32(math.MinInt32), int64(math.MaxInt64),
+		uint8(math.MaxUint8), uint32(math.MaxUint32), uint64(math.MaxInt64), float32(1.5),
+	}
+	for _, v := range typed {
+		obj, err := FromGo(v)
+		if err != nil {
+			t.Fatalf("FromGo(%T) returned error: %v", v, err)
+		}
+		back, err := ToGoValue(obj, reflect.TypeOf(v))
+		if err != nil {
+			t.Fatalf("ToGoValue(%s, %T) returned error: %v", obj.Inspect(), v, err)
+		}
+		if back.Interface() != v {
+			t.Errorf("round trip changed value. expected=%v, got=%v", v, back.Interface())
+		}
+	}
+
+	outOfRange := []struct {
+		input Object
+		typ   any
+	}{
+		{&Integer{Value: 128}, int8(0)},
+		{&Integer{Value: math.MinInt32 - 1}, int32(0)},
+		{&Integer{Value: -1}, uint(0)},
+		{&Integer{Value: 256}, uint8(0)},
+		{&Integer{Value: math.MaxUint32 + 1}, uint32(0)},
+		{&Float{Value: 1e300}, float32(0)},
+		{&Array{Elements: []Object{&Integer{Value: 1000}}}, []int8{}},
+	}
+	for _, tt := range outOfRange {
+		_, err := ToGoValue(tt.input, reflect.TypeOf(tt.typ))
+		if err == nil || !strings.Contains(err.Error(), "out of range") {
+			t.Errorf("ToGoValue(%s, %T) expected range error. got=%v", tt.input.Inspect(), tt.typ, err)
+		}
+	}
+
+	p := person{Name: "bob", Age: 41, Score: 1.25, Admin: true, Tags: []string{"a", "b"}, Home: &address{City: "bj", Zip: 100}}
+	obj, err := FromGo(p)
+	if err != nil {
+		t.Fatalf("FromGo(person) returned error: %v", err)
+	}
+	v, err := ToGoValue(obj, reflect.TypeOf(person{}))
+	if err != nil {
+		t.Fatalf("ToGoValue(person) returned error: %v", err)
+	}
+	if !reflect.DeepEqual(p, v.Interface()) {
+		t.Errorf("struct round trip changed value. expected=%#v, got=%#v", p, v.Interface())
+	}
+}
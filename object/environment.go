@@ -1,5 +1,7 @@
 package object
 
+import "sort"
+
 type Environment struct {
 	store map[string]Object
 	outer *Environment
@@ -28,3 +30,18 @@ func NewEnclosedEnviroment(outer *Environment) *Environment {
 	env.outer = outer
 	return env
 }
+
+// Outer 返回外层环境，全局环境返回 nil
+func (e *Environment) Outer() *Environment {
+	return e.outer
+}
+
+// Names 返回当前环境（不含外层）中的变量名，按字母排序
+func (e *Environment) Names() []string {
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
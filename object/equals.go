@@ -0,0 +1,61 @@
+package object
+
+// visit 记录正在比较的一对容器，用于处理循环引用
+type visit struct {
+	a, b Object
+}
+
+// Equals 深度比较两个对象，数组和 Hash 按元素比较。
+// 比较过程中再次遇到同一对容器时视为相等，避免循环引用导致无限递归。
+func Equals(a, b Object) bool {
+	return deepEquals(a, b, make(map[visit]bool))
+}
+
+func deepEquals(a, b Object, seen map[visit]bool) bool {
+	switch a := a.(type) {
+	case *Array:
+		o, ok := b.(*Array)
+		if !ok || len(a.Elements) != len(o.Elements) {
+			return false
+		}
+		if a == o {
+			return true
+		}
+
+		v := visit{a, o}
+		if seen[v] {
+			return true
+		}
+		seen[v] = true
+
+		for i, elem := range a.Elements {
+			if !deepEquals(elem, o.Elements[i], seen) {
+				return false
+			}
+		}
+		return true
+	case *Hash:
+		o, ok := b.(*Hash)
+		if !ok || a.Len() != o.Len() {
+			return false
+		}
+		if a == o {
+			return true
+		}
+
+		v := visit{a, o}
+		if seen[v] {
+			return true
+		}
+		seen[v] = true
+
+		for _, pair := range a.Items() {
+			other, ok := o.Get(pair.Key.(Hashable))
+			if !ok || !deepEquals(pair.Value, other.Value, seen) {
+				return false
+			}
+		}
+		return true
+	}
+	return a.Equals(b)
+}
@@ -0,0 +1,103 @@
+package object
+
+import (
+	"fmt"
+	"reflect"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// WrapFunc 通过反射把 Go 函数包装为内置函数。
+// 调用时检查参数个数并把参数转换为函数声明的类型；
+// 函数可以返回 (T)、(T, error)、(error) 或不返回值，返回的 error 会变成脚本中的错误，
+// 函数中的 panic 也会变成错误。返回值转换得到的对象通过 ctx.Alloc 计入内存统计。
+func WrapFunc(name string, fn any) (BuiltinFunction, error) {
+	fv := reflect.ValueOf(fn)
+	ft := fv.Type()
+	if ft.Kind() != reflect.Func {
+		return nil, fmt.Errorf("%s is not a function: %T", name, fn)
+	}
+	if err := checkResults(ft); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return func(ctx *Context, args ...Object) Object {
+		in, err := convertArgs(ft, args)
+		if err != nil {
+			return &Error{Message: err.Error()}
+		}
+
+		out, err := call(name, fv, in)
+		if err != nil {
+			return &Error{Message: err.Error()}
+		}
+		if n := len(out); n > 0 && ft.Out(n-1) == errorType {
+			if !out[n-1].IsNil() {
+				return &Error{Message: out[n-1].Interface().(error).Error()}
+			}
+			out = out[:n-1]
+		}
+		if len(out) == 0 {
+			return NULL
+		}
+
+		obj, err := FromGo(out[0].Interface())
+		if err != nil {
+			return &Error{Message: fmt.Sprintf("%s: %v", name, err)}
+		}
+		if err := ctx.Alloc(DeepSize(obj)); err != nil {
+			return err
+		}
+		return obj
+	}, nil
+}
+
+// call 调用 Go 函数，把其中的 panic 转换为错误
+func call(name string, fv reflect.Value, in []reflect.Value) (out []reflect.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s panicked: %v", name, r)
+		}
+	}()
+	return fv.Call(in), nil
+}
+
+func checkResults(ft reflect.Type) error {
+	switch ft.NumOut() {
+	case 0, 1:
+		return nil
+	case 2:
+		if ft.Out(1) == errorType {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported results, want (T), (T, error), (error) or none")
+}
+
+func convertArgs(ft reflect.Type, args []Object) ([]reflect.Value, error) {
+	numIn := ft.NumIn()
+	if ft.IsVariadic() {
+		if len(args) < numIn-1 {
+			return nil, fmt.Errorf("wrong number of arguments. got=%d, want>=%d", len(args), numIn-1)
+		}
+	} else if len(args) != numIn {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=%d", len(args), numIn)
+	}
+
+	in := make([]reflect.Value, len(args))
+	for idx, arg := range args {
+		var typ reflect.Type
+		if ft.IsVariadic() && idx >= numIn-1 {
+			typ = ft.In(numIn - 1).Elem()
+		} else {
+			typ = ft.In(idx)
+		}
+
+		v, err := ToGoValue(arg, typ)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %w", idx+1, err)
+		}
+		in[idx] = v
+	}
+	return in, nil
+}
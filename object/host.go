@@ -0,0 +1,95 @@
+package object
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// HostObject 包装宿主程序中的 Go 值，脚本通过 obj.field 和 obj.method(args) 访问它。
+// 只有导出并且登记在允许列表中的字段和方法可以访问。
+type HostObject struct {
+	Value any
+
+	members map[string]bool
+}
+
+func NewHostObject(v any, members ...string) *HostObject {
+	h := &HostObject{
+		Value:   v,
+		members: make(map[string]bool, len(members)),
+	}
+	for _, m := range members {
+		h.members[m] = true
+	}
+	return h
+}
+
+func (h *HostObject) Inspect() string {
+	return fmt.Sprintf("host(%T)", h.Value)
+}
+
+func (h *HostObject) Type() ObjectType {
+	return HOST_OBJ
+}
+
+func (h *HostObject) Equals(other Object) bool {
+	return h == other
+}
+
+// Allowed 判断成员是否登记在允许列表中
+func (h *HostObject) Allowed(name string) bool {
+	return h.members[name]
+}
+
+// Members 返回允许访问的成员名
+func (h *HostObject) Members() []string {
+	members := make([]string, 0, len(h.members))
+	for m := range h.members {
+		members = append(members, m)
+	}
+	return members
+}
+
+// Field 读取导出字段，指针会被解引用
+func (h *HostObject) Field(name string) (reflect.Value, bool) {
+	v := reflect.ValueOf(h.Value)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}, false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+
+	sf, ok := v.Type().FieldByName(name)
+	if !ok || !sf.IsExported() {
+		return reflect.Value{}, false
+	}
+	// 经过 nil 的嵌入指针时字段不存在
+	f, err := v.FieldByIndexErr(sf.Index)
+	if err != nil {
+		return reflect.Value{}, false
+	}
+	return f, true
+}
+
+// Method 查找导出方法，包括指针接收者上的方法。
+// Value 不是指针时，指针接收者上的方法作用于 Value 的副本
+func (h *HostObject) Method(name string) (reflect.Value, bool) {
+	v := reflect.ValueOf(h.Value)
+	if !v.IsValid() {
+		return reflect.Value{}, false
+	}
+	if m := v.MethodByName(name); m.IsValid() {
+		return m, true
+	}
+	if v.Kind() == reflect.Pointer {
+		return reflect.Value{}, false
+	}
+	ptr := reflect.New(v.Type())
+	ptr.Elem().Set(v)
+	m := ptr.MethodByName(name)
+	return m, m.IsValid()
+}
@@ -0,0 +1,67 @@
+package object
+
+import "testing"
+
+type counter struct {
+	N int
+}
+
+func (c counter) Get() int {
+	return c.N
+}
+
+func (c *counter) Inc() int {
+	c.N++
+	return c.N
+}
+
+type wrapper struct {
+	*counter
+	Name string
+}
+
+func TestHostObjectMethod(t *testing.T) {
+	tests := []struct {
+		value    any
+		method   string
+		expected int
+	}{
+		{counter{N: 1}, "Get", 1},
+		{counter{N: 1}, "Inc", 2},
+		{&counter{N: 1}, "Get", 1},
+		{&counter{N: 1}, "Inc", 2},
+	}
+
+	for _, tt := range tests {
+		m, ok := NewHostObject(tt.value).Method(tt.method)
+		if !ok {
+			t.Errorf("%T.%s not found", tt.value, tt.method)
+			continue
+		}
+		if got := m.Call(nil)[0].Int(); got != int64(tt.expected) {
+			t.Errorf("%T.%s wrong. expected=%d, got=%d", tt.value, tt.method, tt.expected, got)
+		}
+	}
+
+	for _, v := range []any{counter{}, &counter{}, nil} {
+		if _, ok := NewHostObject(v).Method("Missing"); ok {
+			t.Errorf("%T.Missing found", v)
+		}
+	}
+}
+
+func TestHostObjectField(t *testing.T) {
+	h := NewHostObject(wrapper{Name: "w"})
+	if f, ok := h.Field("Name"); !ok || f.String() != "w" {
+		t.Errorf("Name wrong. got=%v, %t", f, ok)
+	}
+	// 嵌入的指针为 nil 时，通过它提升的字段不存在
+	if _, ok := h.Field("N"); ok {
+		t.Errorf("N found through nil embedded pointer")
+	}
+
+	h = NewHostObject(wrapper{counter: &counter{N: 3}})
+	if f, ok := h.Field("N"); !ok || f.Int() != 3 {
+		t.Errorf("N wrong. got=%v, %t", f, ok)
+	}
+}
@@ -2,14 +2,19 @@ package object
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 	"hash/fnv"
+	"io"
+	"math"
 	"shanyl2400/go_compiler/ast"
+	"strconv"
 	"strings"
 )
 
 const (
 	INTEGER_OBJ = "INTEGER"
+	FLOAT_OBJ   = "FLOAT"
 	BOOLEAN_OBJ = "BOOLEAN"
 	STRING_OBJ  = "STRING"
 	NULL_OBJ    = "NULL"
@@ -19,25 +24,83 @@ const (
 	FUNCTION_OBJ     = "FUNCTION"
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
 	BUILTIN_OBJ      = "BUILTIN"
+	HOST_OBJ         = "HOST"
+	MODULE_OBJ       = "MODULE"
 	ERROR_OBJ        = "ERROR"
 )
 
 type ObjectType string
 
-type BuiltinFunction func(args ...Object) Object
+// TRUE、FALSE 和 NULL 是全局唯一的对象，求值器通过指针比较它们
+var (
+	TRUE  = &Boolean{Value: true}
+	FALSE = &Boolean{Value: false}
+
+	NULL = &Null{}
+)
+
+// Context 是内置函数执行时可以访问的上下文
+type Context struct {
+	Stdout io.Writer
+	Stderr io.Writer
+
+	alloc func(size int64) *Error
+}
+
+func NewContext(stdout, stderr io.Writer) *Context {
+	return &Context{Stdout: stdout, Stderr: stderr}
+}
+
+// SetAllocator 设置内存统计函数，由求值器在创建时设置
+func (c *Context) SetAllocator(alloc func(size int64) *Error) {
+	c.alloc = alloc
+}
+
+// Alloc 报告内置函数创建数组、Hash 或字符串时估算的字节数，
+// 返回非 nil 时内置函数应直接返回该错误
+func (c *Context) Alloc(size int64) *Error {
+	if c.alloc == nil {
+		return nil
+	}
+	return c.alloc(size)
+}
+
+type BuiltinFunction func(ctx *Context, args ...Object) Object
 
 type HashKey struct {
 	Type  ObjectType
 	Value uint64
 }
 
+// Hashable 可以作为 Hash 键的对象，HashKey 相同时通过 Equals 区分不同的键
 type Hashable interface {
+	Object
 	HashKey() HashKey
 }
 
+// AsHashable 判断 obj 能否作为 Hash 的键，数组要求所有元素都能作为键。
+// NaN 不等于任何值，作为键既查不到也无法覆盖，因此不能作为键
+func AsHashable(obj Object) (Hashable, bool) {
+	if f, ok := obj.(*Float); ok && math.IsNaN(f.Value) {
+		return nil, false
+	}
+	if arr, ok := obj.(*Array); ok {
+		for _, elem := range arr.Elements {
+			if _, ok := AsHashable(elem); !ok {
+				return nil, false
+			}
+		}
+		return arr, true
+	}
+	h, ok := obj.(Hashable)
+	return h, ok
+}
+
 type Object interface {
 	Type() ObjectType
 	Inspect() string
+	// Equals 判断结构相等，数组和 Hash 会逐个比较元素
+	Equals(other Object) bool
 }
 
 type Integer struct {
@@ -59,6 +122,40 @@ func (i *Integer) HashKey() HashKey {
 	}
 }
 
+func (i *Integer) Equals(other Object) bool {
+	o, ok := other.(*Integer)
+	return ok && o.Value == i.Value
+}
+
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Inspect() string {
+	return strconv.FormatFloat(f.Value, 'g', -1, 64)
+}
+
+func (f *Float) Type() ObjectType {
+	return FLOAT_OBJ
+}
+
+// HashKey 把 -0 当作 0，与 Equals 一致。NaN 不等于自身，不能作为键，见 AsHashable
+func (f *Float) HashKey() HashKey {
+	value := f.Value
+	if value == 0 {
+		value = 0
+	}
+	return HashKey{
+		Type:  f.Type(),
+		Value: math.Float64bits(value),
+	}
+}
+
+func (f *Float) Equals(other Object) bool {
+	o, ok := other.(*Float)
+	return ok && o.Value == f.Value
+}
+
 type Boolean struct {
 	Value bool
 }
@@ -82,6 +179,11 @@ func (b *Boolean) HashKey() HashKey {
 	}
 }
 
+func (b *Boolean) Equals(other Object) bool {
+	o, ok := other.(*Boolean)
+	return ok && o.Value == b.Value
+}
+
 type String struct {
 	Value string
 }
@@ -104,6 +206,11 @@ func (s *String) HashKey() HashKey {
 	}
 }
 
+func (s *String) Equals(other Object) bool {
+	o, ok := other.(*String)
+	return ok && o.Value == s.Value
+}
+
 type ReturnValue struct {
 	Value Object
 }
@@ -116,6 +223,11 @@ func (r *ReturnValue) Type() ObjectType {
 	return RETURN_VALUE_OBJ
 }
 
+func (r *ReturnValue) Equals(other Object) bool {
+	o, ok := other.(*ReturnValue)
+	return ok && Equals(r.Value, o.Value)
+}
+
 type Null struct {
 }
 
@@ -127,10 +239,20 @@ func (n *Null) Type() ObjectType {
 	return NULL_OBJ
 }
 
+func (n *Null) Equals(other Object) bool {
+	_, ok := other.(*Null)
+	return ok
+}
+
 type Function struct {
 	Parameters []*ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
+
+	// Name 是定义时 let 绑定的名字，匿名函数为空
+	Name string
+	// File 是定义函数的源码文件
+	File string
 }
 
 func (f *Function) Inspect() string {
@@ -151,10 +273,24 @@ func (f *Function) Inspect() string {
 	return out.String()
 }
 
+// Signature 返回不含函数体的 fn(a, b)，用于调试器和 REPL 的简短显示
+func (f *Function) Signature() string {
+	params := make([]string, len(f.Parameters))
+	for i, p := range f.Parameters {
+		params[i] = p.Value
+	}
+	return "fn(" + strings.Join(params, ", ") + ")"
+}
+
 func (f *Function) Type() ObjectType {
 	return FUNCTION_OBJ
 }
 
+// Equals 函数只和自身相等
+func (f *Function) Equals(other Object) bool {
+	return f == other
+}
+
 type Builtin struct {
 	Fn BuiltinFunction
 }
@@ -167,6 +303,28 @@ func (b *Builtin) Type() ObjectType {
 	return BUILTIN_OBJ
 }
 
+func (b *Builtin) Equals(other Object) bool {
+	return b == other
+}
+
+// Module 是 import 得到的模块，Exports 保存模块中 export 的变量
+type Module struct {
+	Path    string
+	Exports *Hash
+}
+
+func (m *Module) Inspect() string {
+	return "module(" + m.Path + ")"
+}
+
+func (m *Module) Type() ObjectType {
+	return MODULE_OBJ
+}
+
+func (m *Module) Equals(other Object) bool {
+	return m == other
+}
+
 type Array struct {
 	Elements []Object
 }
@@ -189,19 +347,114 @@ func (a *Array) Type() ObjectType {
 	return ARRAY_OBJ
 }
 
+// HashKey 由元素的 HashKey 组合而成，只应在 AsHashable 检查通过后调用
+func (a *Array) HashKey() HashKey {
+	h := fnv.New64a()
+	buf := make([]byte, 8)
+	for _, elem := range a.Elements {
+		key := elem.(Hashable).HashKey()
+		h.Write([]byte(key.Type))
+		binary.BigEndian.PutUint64(buf, key.Value)
+		h.Write(buf)
+	}
+
+	return HashKey{
+		Type:  a.Type(),
+		Value: h.Sum64(),
+	}
+}
+
+func (a *Array) Equals(other Object) bool {
+	return Equals(a, other)
+}
+
 type HashPair struct {
 	Key   Object
 	Value Object
 }
 
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	// buckets 按 HashKey 分桶，桶内通过 Equals 区分发生碰撞的键
+	buckets map[HashKey][]*HashPair
+
+	// order 记录键值对的插入顺序，保证遍历和 Inspect 结果稳定
+	order []*HashPair
+}
+
+func NewHash() *Hash {
+	return &Hash{buckets: make(map[HashKey][]*HashPair)}
 }
 
-func (a *Hash) Inspect() string {
+func (h *Hash) lookup(key Hashable) (*HashPair, bool) {
+	for _, pair := range h.buckets[key.HashKey()] {
+		if key.Equals(pair.Key) {
+			return pair, true
+		}
+	}
+	return nil, false
+}
+
+// Set 写入键值对，已存在的键保持原有位置
+func (h *Hash) Set(key Hashable, value Object) {
+	if pair, ok := h.lookup(key); ok {
+		pair.Value = value
+		return
+	}
+
+	pair := &HashPair{Key: key, Value: value}
+	hashKey := key.HashKey()
+	h.buckets[hashKey] = append(h.buckets[hashKey], pair)
+	h.order = append(h.order, pair)
+}
+
+func (h *Hash) Get(key Hashable) (HashPair, bool) {
+	if pair, ok := h.lookup(key); ok {
+		return *pair, true
+	}
+	return HashPair{}, false
+}
+
+func (h *Hash) Delete(key Hashable) bool {
+	pair, ok := h.lookup(key)
+	if !ok {
+		return false
+	}
+
+	hashKey := key.HashKey()
+	h.buckets[hashKey] = removePair(h.buckets[hashKey], pair)
+	if len(h.buckets[hashKey]) == 0 {
+		delete(h.buckets, hashKey)
+	}
+	h.order = removePair(h.order, pair)
+	return true
+}
+
+func (h *Hash) Len() int {
+	return len(h.order)
+}
+
+// Items 按插入顺序返回所有键值对
+func (h *Hash) Items() []HashPair {
+	items := make([]HashPair, 0, len(h.order))
+	for _, pair := range h.order {
+		items = append(items, *pair)
+	}
+	return items
+}
+
+func removePair(pairs []*HashPair, target *HashPair) []*HashPair {
+	for i, pair := range pairs {
+		if pair == target {
+			return append(pairs[:i:i], pairs[i+1:]...)
+		}
+	}
+	return pairs
+}
+
+func (h *Hash) Inspect() string {
 	var out bytes.Buffer
 	pairs := make([]string, 0)
-	for _, pair := range a.Pairs {
+	for _, pair := range h.Items() {
 		pairs = append(pairs, pair.Key.Inspect()+": "+pair.Value.Inspect())
 	}
 
@@ -216,8 +469,45 @@ func (h *Hash) Type() ObjectType {
 	return HASH_OBJ
 }
 
+func (h *Hash) Equals(other Object) bool {
+	return Equals(h, other)
+}
+
 type Error struct {
 	Message string
+
+	// Err 是导致错误的 Go 错误，例如超出执行限制，普通的脚本错误为 nil
+	Err error
+
+	// Stack 是出错时的调用栈，最内层的调用在前
+	Stack []StackFrame
+
+	// Value 是 throw 抛出的值，运行时错误为 nil
+	Value Object
+}
+
+// StackFrame 是调用栈中的一层，Line 是该函数执行到的行
+type StackFrame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+func (f StackFrame) String() string {
+	file := f.File
+	if file == "" {
+		file = "<input>"
+	}
+	return fmt.Sprintf("%s (%s:%d)", f.Function, file, f.Line)
+}
+
+// StackTrace 返回调用栈的文本，每层一行
+func (e *Error) StackTrace() string {
+	var out bytes.Buffer
+	for _, frame := range e.Stack {
+		out.WriteString("    at " + frame.String() + "\n")
+	}
+	return out.String()
 }
 
 func (e *Error) Inspect() string {
@@ -227,3 +517,8 @@ func (e *Error) Inspect() string {
 func (e *Error) Type() ObjectType {
 	return ERROR_OBJ
 }
+
+func (e *Error) Equals(other Object) bool {
+	o, ok := other.(*Error)
+	return ok && o.Message == e.Message
+}
@@ -1,6 +1,9 @@
 package object
 
-import "testing"
+import (
+	"math"
+	"testing"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -20,3 +23,129 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash keys")
 	}
 }
+
+func TestHashOrder(t *testing.T) {
+	h := NewHash()
+	h.Set(&String{Value: "b"}, &Integer{Value: 1})
+	h.Set(&String{Value: "a"}, &Integer{Value: 2})
+	h.Set(&Integer{Value: 3}, &Integer{Value: 3})
+	h.Set(&String{Value: "b"}, &Integer{Value: 4})
+
+	if h.Inspect() != "{b: 4, a: 2, 3: 3}" {
+		t.Errorf("hash has wrong order. got=%q", h.Inspect())
+	}
+
+	if !h.Delete(&String{Value: "a"}) {
+		t.Errorf("delete existing key returned false")
+	}
+	if h.Delete(&String{Value: "a"}) {
+		t.Errorf("delete missing key returned true")
+	}
+	if h.Len() != 2 || h.Inspect() != "{b: 4, 3: 3}" {
+		t.Errorf("hash has wrong pairs after delete. got=%q", h.Inspect())
+	}
+}
+
+// collidingKey 的 HashKey 恒定，用来模拟哈希碰撞
+type collidingKey struct {
+	String
+}
+
+func (c *collidingKey) HashKey() HashKey {
+	return HashKey{Type: STRING_OBJ, Value: 42}
+}
+
+func (c *collidingKey) Equals(other Object) bool {
+	o, ok := other.(*collidingKey)
+	return ok && o.Value == c.Value
+}
+
+func TestHashCollision(t *testing.T) {
+	h := NewHash()
+	a := &collidingKey{String{Value: "a"}}
+	b := &collidingKey{String{Value: "b"}}
+	h.Set(a, &Integer{Value: 1})
+	h.Set(b, &Integer{Value: 2})
+
+	if h.Len() != 2 {
+		t.Fatalf("colliding keys overwrite each other. got=%q", h.Inspect())
+	}
+
+	pair, ok := h.Get(a)
+	if !ok || pair.Value.Inspect() != "1" {
+		t.Errorf("wrong value for key a. got=%v", pair.Value)
+	}
+	pair, ok = h.Get(b)
+	if !ok || pair.Value.Inspect() != "2" {
+		t.Errorf("wrong value for key b. got=%v", pair.Value)
+	}
+
+	h.Delete(a)
+	if _, ok := h.Get(b); !ok || h.Len() != 1 {
+		t.Errorf("delete removed the wrong colliding key. got=%q", h.Inspect())
+	}
+}
+
+func TestArrayHashKey(t *testing.T) {
+	arr1 := &Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "a"}}}
+	arr2 := &Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "a"}}}
+	diff := &Array{Elements: []Object{&String{Value: "a"}, &Integer{Value: 1}}}
+
+	if arr1.HashKey() != arr2.HashKey() || !arr1.Equals(arr2) {
+		t.Errorf("arrays with same elements are different keys")
+	}
+	if arr1.Equals(diff) {
+		t.Errorf("arrays with different elements are equal keys")
+	}
+
+	unhashable := &Array{Elements: []Object{&Integer{Value: 1}, &Null{}}}
+	if _, ok := AsHashable(unhashable); ok {
+		t.Errorf("array with unhashable element is hashable")
+	}
+}
+
+func TestFloatHashKey(t *testing.T) {
+	zero := &Float{Value: 0}
+	negZero := &Float{Value: math.Copysign(0, -1)}
+	if zero.HashKey() != negZero.HashKey() || !zero.Equals(negZero) {
+		t.Errorf("0 and -0 are different keys")
+	}
+
+	hash := NewHash()
+	hash.Set(zero, &Integer{Value: 1})
+	if pair, ok := hash.Get(negZero); !ok || pair.Value.(*Integer).Value != 1 {
+		t.Errorf("-0 not found in hash with key 0")
+	}
+
+	nan := &Float{Value: math.NaN()}
+	if _, ok := AsHashable(nan); ok {
+		t.Errorf("NaN is hashable")
+	}
+	if _, ok := AsHashable(&Array{Elements: []Object{nan}}); ok {
+		t.Errorf("array containing NaN is hashable")
+	}
+}
+
+func TestEqualsCycle(t *testing.T) {
+	a := &Array{Elements: []Object{&Integer{Value: 1}, nil}}
+	a.Elements[1] = a
+	b := &Array{Elements: []Object{&Integer{Value: 1}, nil}}
+	b.Elements[1] = b
+
+	if !Equals(a, b) {
+		t.Errorf("cyclic arrays with same shape are not equal")
+	}
+
+	h1 := NewHash()
+	h2 := NewHash()
+	h1.Set(&String{Value: "self"}, h1)
+	h2.Set(&String{Value: "self"}, h2)
+	if !Equals(h1, h2) {
+		t.Errorf("cyclic hashes with same shape are not equal")
+	}
+
+	h2.Set(&String{Value: "other"}, &Integer{Value: 1})
+	if Equals(h1, h2) {
+		t.Errorf("cyclic hashes with different pairs are equal")
+	}
+}
@@ -0,0 +1,40 @@
+package object
+
+// 估算对象占用的字节数，只用于限制脚本的分配量，不追求精确
+const (
+	stringHeaderSize = 16
+	arrayHeaderSize  = 24
+	arrayElementSize = 16
+	hashHeaderSize   = 48
+	hashPairSize     = 64
+)
+
+// Size 返回新建对象本身的字节数，不包括数组和 Hash 中已经存在的元素，
+// 字符串、数组和 Hash 以外的对象不计入
+func Size(obj Object) int64 {
+	switch obj := obj.(type) {
+	case *String:
+		return stringHeaderSize + int64(len(obj.Value))
+	case *Array:
+		return arrayHeaderSize + int64(len(obj.Elements))*arrayElementSize
+	case *Hash:
+		return hashHeaderSize + int64(obj.Len())*hashPairSize
+	}
+	return 0
+}
+
+// DeepSize 返回对象及其包含的元素的总字节数，用于 FromGo 等一次创建整棵对象的情况
+func DeepSize(obj Object) int64 {
+	size := Size(obj)
+	switch obj := obj.(type) {
+	case *Array:
+		for _, elem := range obj.Elements {
+			size += DeepSize(elem)
+		}
+	case *Hash:
+		for _, pair := range obj.Items() {
+			size += DeepSize(pair.Key) + DeepSize(pair.Value)
+		}
+	}
+	return size
+}
@@ -667,6 +667,38 @@ func TestParsingArrayLiterals(t *testing.T) {
 	testInfixExpression(t, array.Elements[2], 3, "+", 3)
 }
 
+func TestParsingEmptyArrayLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[]", "[]"},
+		{"[[], 1]", "[[], 1]"},
+		{"f([])", "f([])"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	program := New(lexer.New("[]")).ParseProgram()
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	array, ok := stmt.Expression.(*ast.ArrayLiteral)
+	if !ok {
+		t.Fatalf("exp not ast.ArrayLiteral. got=%T", stmt.Expression)
+	}
+	if len(array.Elements) != 0 {
+		t.Errorf("len(array.Elements) not 0. got=%d", len(array.Elements))
+	}
+}
+
 func TestParsingIndexExpressions(t *testing.T) {
 	input := "myArray[1 + 1]"
 
@@ -792,6 +824,102 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 	}
 }
 
+func TestParsingNullishExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"null", "null"},
+		{"a ?? b", "(a ?? b)"},
+		{"a ?? b == c", "(a ?? (b == c))"},
+		{"a.b.c", "((a.b).c)"},
+		{"a?.b ?? 1", "((a?.b) ?? 1)"},
+		{"a?[0]?.b", "((a?[0])?.b)"},
+		{`a["x"].y`, "((a[x]).y)"},
+		{"log.Log(1, 2)", "(log.Log)(1,2)"},
+		{"a.b.c(x)[0]", "(((a.b).c)(x)[0])"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		actual := program.String()
+		if actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestParsingTryExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"throw x", "throw x;"},
+		{`throw "a" + b;`, "throw (a + b);"},
+		{"try { a } catch (e) { b }", "try acatch (e) b"},
+		{"try { a } catch { b }", "try acatch b"},
+		{"try { a } finally { c }", "try afinally c"},
+		{"try { a } catch (e) { b } finally { c }", "try acatch (e) bfinally c"},
+		{"let x = try { a } catch (e) { b };", "let x = try acatch (e) b;"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		actual := program.String()
+		if actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestParsingModules(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "lib/math"`, `import "lib/math"`},
+		{`let m = import "./m";`, `let m = import "./m";`},
+		{`export let x = 1;`, `export let x = 1;`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		actual := program.String()
+		if actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+
+	for _, input := range []string{`export x`, `import x`, `fn() { export let x = 1; }`} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected parser error for %q", input)
+		}
+	}
+}
+
+func TestParsingTryWithoutHandler(t *testing.T) {
+	p := New(lexer.New("try { a }"))
+	p.ParseProgram()
+
+	if len(p.Errors()) == 0 {
+		t.Fatalf("expected parser error for try without catch or finally")
+	}
+}
+
 func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
 	if s.TokenLiteral() != "let" {
 		t.Errorf("s.TokenLiteral not 'let'. got=%q", s.TokenLiteral())
@@ -916,3 +1044,50 @@ func checkParserErrors(t *testing.T, p *Parser) {
 	}
 	t.FailNow()
 }
+
+func TestErrorPositions(t *testing.T) {
+	p := New(lexer.New("let x = 1;\nlet = 2;\nlet y = ;"))
+	p.ParseProgram()
+
+	expected := []string{
+		"2:5: expected next token to be IDENT, got = instead",
+		"2:5: no prefix parse function for = found",
+		"3:9: no prefix parse function for ; found",
+	}
+	errs := p.ErrorList()
+	if len(errs) != len(expected) {
+		t.Fatalf("wrong number of errors. expected=%d, got=%d (%v)", len(expected), len(errs), p.Errors())
+	}
+	for i, err := range errs {
+		if err.Error() != expected[i] {
+			t.Errorf("errors[%d] wrong. expected=%q, got=%q", i, expected[i], err.Error())
+		}
+	}
+}
+
+func TestParsingComments(t *testing.T) {
+	input := "// head\nlet x = 10 / 2; // tail\n  // indented\nx // end"
+	program := New(lexer.New(input)).ParseProgram()
+
+	tests := []struct {
+		text     string
+		line     int
+		trailing bool
+	}{
+		{"// head", 1, false},
+		{"// tail", 2, true},
+		{"// indented", 3, false},
+		{"// end", 4, true},
+	}
+
+	if len(program.Comments) != len(tests) {
+		t.Fatalf("wrong number of comments. expected=%d, got=%d", len(tests), len(program.Comments))
+	}
+	for i, tt := range tests {
+		c := program.Comments[i]
+		if c.Text() != tt.text || c.Token.Line != tt.line || c.Trailing != tt.trailing {
+			t.Errorf("comments[%d] wrong. expected=%q line %d trailing=%t, got=%q line %d trailing=%t",
+				i, tt.text, tt.line, tt.trailing, c.Text(), c.Token.Line, c.Trailing)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package repl
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"shanyl2400/go_compiler/ast"
+	"shanyl2400/go_compiler/compiler"
+	"shanyl2400/go_compiler/evaluator"
+	"shanyl2400/go_compiler/lexer"
+	"shanyl2400/go_compiler/object"
+	"shanyl2400/go_compiler/parser"
+	"strings"
+	"time"
+)
+
+// session 保存 REPL 的环境和求值器，:reset 时重新创建
+type session struct {
+	out  io.Writer
+	env  *object.Environment
+	eval *evaluator.Evaluator
+}
+
+func newSession(out io.Writer) *session {
+	s := &session{out: out}
+	s.reset()
+	return s
+}
+
+func (s *session) reset() {
+	s.env = object.NewEnvironment()
+	s.eval = evaluator.New(object.NewContext(s.out, s.out))
+}
+
+// parse 解析输入，有错误时打印错误并返回 nil
+func (s *session) parse(input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParseErrors(s.out, p.Errors())
+		return nil
+	}
+	return program
+}
+
+// run 在当前环境中执行程序并打印结果
+func (s *session) run(program *ast.Program) object.Object {
+	evaluated := s.eval.Eval(program, s.env)
+	s.print(evaluated)
+	return evaluated
+}
+
+func (s *session) print(evaluated object.Object) {
+	if evaluated != nil {
+		io.WriteString(s.out, evaluated.Inspect())
+		io.WriteString(s.out, "\n")
+	}
+	if errObj, ok := evaluated.(*object.Error); ok {
+		io.WriteString(s.out, errObj.StackTrace())
+	}
+}
+
+type command struct {
+	name string
+	args string
+	help string
+	run  func(s *session, arg string)
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"help", "", "show this list", (*session).cmdHelp},
+		{"env", "", "list the bindings in the environment", (*session).cmdEnv},
+		{"type", "EXPR", "evaluate EXPR and show the type of its value", (*session).cmdType},
+		{"ast", "EXPR", "show the syntax tree of EXPR without running it", (*session).cmdAST},
+		{"bytecode", "EXPR", "show the instructions compiled from EXPR", (*session).cmdBytecode},
+		{"load", "FILE", "run FILE in the current environment", (*session).cmdLoad},
+		{"reset", "", "clear all bindings and loaded modules", (*session).cmdReset},
+		{"time", "EXPR", "evaluate EXPR and show how long it took", (*session).cmdTime},
+	}
+}
+
+// command 执行以冒号开头的命令
+func (s *session) command(input string) {
+	name, arg, _ := strings.Cut(strings.TrimPrefix(strings.TrimSpace(input), ":"), " ")
+	arg = strings.TrimSpace(arg)
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		if cmd.args != "" && arg == "" {
+			fmt.Fprintf(s.out, "usage: :%s %s\n", cmd.name, cmd.args)
+			return
+		}
+		cmd.run(s, arg)
+		return
+	}
+	fmt.Fprintf(s.out, "unknown command: :%s (type :help for a list)\n", name)
+}
+
+func (s *session) cmdHelp(string) {
+	for _, cmd := range commands {
+		usage := ":" + cmd.name
+		if cmd.args != "" {
+			usage += " " + cmd.args
+		}
+		fmt.Fprintf(s.out, "  %-16s %s\n", usage, cmd.help)
+	}
+}
+
+func (s *session) cmdEnv(string) {
+	for _, name := range s.env.Names() {
+		val, _ := s.env.Get(name)
+		fmt.Fprintf(s.out, "%s = %s\n", name, describe(val))
+	}
+}
+
+// describe 函数只显示参数，其他值显示 Inspect 的结果
+func describe(obj object.Object) string {
+	if fn, ok := obj.(*object.Function); ok {
+		return fn.Signature()
+	}
+	return obj.Inspect()
+}
+
+func (s *session) cmdType(arg string) {
+	program := s.parse(arg)
+	if program == nil {
+		return
+	}
+	evaluated := s.eval.Eval(program, s.env)
+	if evaluated == nil {
+		evaluated = evaluator.NULL
+	}
+	if _, ok := evaluated.(*object.Error); ok {
+		s.print(evaluated)
+		return
+	}
+	fmt.Fprintln(s.out, evaluated.Type())
+}
+
+func (s *session) cmdAST(arg string) {
+	if program := s.parse(arg); program != nil {
+		ast.Fprint(s.out, program)
+	}
+}
+
+func (s *session) cmdBytecode(arg string) {
+	program := s.parse(arg)
+	if program == nil {
+		return
+	}
+	c := compiler.New()
+	if err := c.Compile(program); err != nil {
+		fmt.Fprintf(s.out, "compile error: %s\n", err)
+		return
+	}
+
+	bytecode := c.ByteCode()
+	if len(bytecode.Instructions) == 0 {
+		// 编译器目前只是骨架，还不会生成指令
+		fmt.Fprintln(s.out, "no instructions: the compiler does not generate bytecode for this code yet")
+		return
+	}
+	fmt.Fprint(s.out, bytecode.Instructions.String())
+	for i, constant := range bytecode.Constants {
+		fmt.Fprintf(s.out, "constant %d: %s\n", i, constant.Inspect())
+	}
+}
+
+func (s *session) cmdLoad(arg string) {
+	src, err := os.ReadFile(arg)
+	if err != nil {
+		fmt.Fprintln(s.out, err)
+		return
+	}
+	program := s.parse(string(src))
+	if program == nil {
+		return
+	}
+	program.File = arg
+	s.run(program)
+}
+
+func (s *session) cmdReset(string) {
+	s.reset()
+	fmt.Fprintln(s.out, "environment cleared")
+}
+
+func (s *session) cmdTime(arg string) {
+	program := s.parse(arg)
+	if program == nil {
+		return
+	}
+	start := time.Now()
+	s.run(program)
+	fmt.Fprintf(s.out, "took %s\n", time.Since(start))
+}
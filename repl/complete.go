@@ -0,0 +1,101 @@
+package repl
+
+import (
+	"regexp"
+	"shanyl2400/go_compiler/evaluator"
+	"shanyl2400/go_compiler/object"
+	"shanyl2400/go_compiler/token"
+	"sort"
+	"strings"
+)
+
+// hashIndex 匹配光标前的 h[ 或 h["ke，第一组是变量名，第二组是已经输入的键
+var hashIndex = regexp.MustCompile(`([A-Za-z_]+)\??\[\s*("[^"]*)?$`)
+
+// complete 根据光标前的内容 before 返回候选项，以及候选项要替换的 before 末尾的字节数。
+// 候选项包括环境中的变量、内置函数和关键字，在已知 Hash 的 [ 之后是它的字符串键。
+func (s *session) complete(before string) ([]string, int) {
+	if m := hashIndex.FindStringSubmatch(before); m != nil {
+		return s.hashKeys(m[1], m[2]), len(m[2])
+	}
+	// 字符串中不补全
+	if strings.Count(before, `"`)%2 == 1 {
+		return nil, 0
+	}
+
+	start := len(before)
+	for start > 0 && isIdentChar(before[start-1]) {
+		start--
+	}
+	word := before[start:]
+	if word == "" || (start > 0 && before[start-1] == '.') {
+		return nil, 0
+	}
+
+	seen := make(map[string]bool)
+	var candidates []string
+	add := func(names []string) {
+		for _, name := range names {
+			if strings.HasPrefix(name, word) && !seen[name] {
+				seen[name] = true
+				candidates = append(candidates, name)
+			}
+		}
+	}
+	for env := s.env; env != nil; env = env.Outer() {
+		add(env.Names())
+	}
+	add(evaluator.BuiltinNames())
+	add(token.Keywords())
+
+	sort.Strings(candidates)
+	return candidates, len(word)
+}
+
+// hashKeys 返回变量 name 的字符串键中以 typed 开头的部分，补全后带上右括号
+func (s *session) hashKeys(name, typed string) []string {
+	obj, ok := s.env.Get(name)
+	if !ok {
+		return nil
+	}
+	var hash *object.Hash
+	switch obj := obj.(type) {
+	case *object.Hash:
+		hash = obj
+	case *object.Module:
+		hash = obj.Exports
+	default:
+		return nil
+	}
+
+	var candidates []string
+	for _, pair := range hash.Items() {
+		key, ok := pair.Key.(*object.String)
+		if !ok {
+			continue
+		}
+		candidate := `"` + key.Value + `"]`
+		if strings.HasPrefix(candidate, typed) {
+			candidates = append(candidates, candidate)
+		}
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+func isIdentChar(ch byte) bool {
+	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || ch == '_'
+}
+
+// commonPrefix 返回所有候选项的最长公共前缀
+func commonPrefix(candidates []string) string {
+	prefix := candidates[0]
+	for _, c := range candidates[1:] {
+		n := 0
+		for n < len(prefix) && n < len(c) && prefix[n] == c[n] {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+	return prefix
+}
@@ -0,0 +1,261 @@
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// 控制键
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyBackspace = 8
+	keyTab       = 9
+	keyCtrlK     = 11
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlU     = 21
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+// terminal 在读取一行时把终端切换到原始模式，执行代码时恢复
+type terminal struct {
+	fd int
+	*editor
+}
+
+func (t *terminal) ReadLine(prompt string) (string, error) {
+	state, err := term.MakeRaw(t.fd)
+	if err != nil {
+		return "", err
+	}
+	defer term.Restore(t.fd, state)
+	return t.editor.ReadLine(prompt)
+}
+
+// editor 实现行编辑：左右方向键移动光标，上下方向键浏览历史记录
+type editor struct {
+	in      *bufio.Reader
+	out     io.Writer
+	history *history
+
+	// complete 返回 Tab 补全的候选项和要替换的光标前的字节数，为 nil 时不补全
+	complete func(before string) ([]string, int)
+
+	buf    []rune
+	cursor int
+	prompt string
+
+	// index 是正在显示的历史记录，等于 len(entries) 时显示 draft
+	index int
+	draft []rune
+}
+
+func newEditor(in io.Reader, out io.Writer, history *history) *editor {
+	return &editor{in: bufio.NewReader(in), out: out, history: history}
+}
+
+func (ed *editor) ReadLine(prompt string) (string, error) {
+	ed.buf, ed.cursor, ed.prompt = nil, 0, prompt
+	ed.index, ed.draft = len(ed.history.entries), nil
+	ed.refresh()
+
+	for {
+		r, _, err := ed.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		switch r {
+		case keyEnter, '\n':
+			fmt.Fprint(ed.out, "\r\n")
+			line := string(ed.buf)
+			ed.history.add(line)
+			return line, nil
+		case keyCtrlC:
+			fmt.Fprint(ed.out, "^C\r\n")
+			return "", errInterrupt
+		case keyCtrlD:
+			if len(ed.buf) == 0 {
+				fmt.Fprint(ed.out, "\r\n")
+				return "", io.EOF
+			}
+			ed.delete()
+		case keyDelete, keyBackspace:
+			if ed.cursor > 0 {
+				ed.cursor--
+				ed.delete()
+			}
+		case keyCtrlA:
+			ed.cursor = 0
+		case keyCtrlE:
+			ed.cursor = len(ed.buf)
+		case keyCtrlB:
+			ed.left()
+		case keyCtrlF:
+			ed.right()
+		case keyCtrlK:
+			ed.buf = ed.buf[:ed.cursor]
+		case keyCtrlU:
+			ed.buf = append([]rune(nil), ed.buf[ed.cursor:]...)
+			ed.cursor = 0
+		case keyCtrlP:
+			ed.prev()
+		case keyCtrlN:
+			ed.next()
+		case keyTab:
+			ed.completeWord()
+		case keyEscape:
+			if err := ed.escape(); err != nil {
+				return "", err
+			}
+		default:
+			if r >= ' ' {
+				ed.insert(r)
+			}
+		}
+		ed.refresh()
+	}
+}
+
+// escape 处理方向键等以 ESC [ 或 ESC O 开始的转义序列
+func (ed *editor) escape() error {
+	r, _, err := ed.in.ReadRune()
+	if err != nil || (r != '[' && r != 'O') {
+		return err
+	}
+
+	var param []rune
+	for {
+		r, _, err = ed.in.ReadRune()
+		if err != nil {
+			return err
+		}
+		if r < '0' || r > '9' {
+			break
+		}
+		param = append(param, r)
+	}
+
+	switch r {
+	case 'A':
+		ed.prev()
+	case 'B':
+		ed.next()
+	case 'C':
+		ed.right()
+	case 'D':
+		ed.left()
+	case 'H':
+		ed.cursor = 0
+	case 'F':
+		ed.cursor = len(ed.buf)
+	case '~':
+		switch string(param) {
+		case "1", "7":
+			ed.cursor = 0
+		case "4", "8":
+			ed.cursor = len(ed.buf)
+		case "3":
+			ed.delete()
+		}
+	}
+	return nil
+}
+
+// completeWord 补全候选项的公共前缀，无法继续补全时列出所有候选项
+func (ed *editor) completeWord() {
+	if ed.complete == nil {
+		return
+	}
+	before := string(ed.buf[:ed.cursor])
+	candidates, n := ed.complete(before)
+	if len(candidates) == 0 {
+		return
+	}
+
+	word := before[len(before)-n:]
+	if prefix := commonPrefix(candidates); len(prefix) > len(word) {
+		start := ed.cursor - utf8.RuneCountInString(word)
+		rest := append([]rune(prefix), ed.buf[ed.cursor:]...)
+		ed.buf = append(ed.buf[:start], rest...)
+		ed.cursor = start + utf8.RuneCountInString(prefix)
+		return
+	}
+	if len(candidates) > 1 {
+		fmt.Fprintf(ed.out, "\r\n%s\r\n", strings.Join(candidates, "  "))
+	}
+}
+
+func (ed *editor) insert(r rune) {
+	ed.buf = append(ed.buf, 0)
+	copy(ed.buf[ed.cursor+1:], ed.buf[ed.cursor:])
+	ed.buf[ed.cursor] = r
+	ed.cursor++
+}
+
+// delete 删除光标处的字符
+func (ed *editor) delete() {
+	if ed.cursor < len(ed.buf) {
+		ed.buf = append(ed.buf[:ed.cursor], ed.buf[ed.cursor+1:]...)
+	}
+}
+
+func (ed *editor) left() {
+	if ed.cursor > 0 {
+		ed.cursor--
+	}
+}
+
+func (ed *editor) right() {
+	if ed.cursor < len(ed.buf) {
+		ed.cursor++
+	}
+}
+
+func (ed *editor) prev() {
+	if ed.index == 0 {
+		return
+	}
+	if ed.index == len(ed.history.entries) {
+		ed.draft = ed.buf
+	}
+	ed.index--
+	ed.show([]rune(ed.history.entries[ed.index]))
+}
+
+func (ed *editor) next() {
+	if ed.index == len(ed.history.entries) {
+		return
+	}
+	ed.index++
+	if ed.index == len(ed.history.entries) {
+		ed.show(ed.draft)
+	} else {
+		ed.show([]rune(ed.history.entries[ed.index]))
+	}
+}
+
+func (ed *editor) show(line []rune) {
+	ed.buf = append([]rune(nil), line...)
+	ed.cursor = len(ed.buf)
+}
+
+// refresh 重新显示提示符和当前行，再把光标移回原位
+func (ed *editor) refresh() {
+	fmt.Fprintf(ed.out, "\r%s%s\x1b[K", ed.prompt, string(ed.buf))
+	if n := len(ed.buf) - ed.cursor; n > 0 {
+		fmt.Fprintf(ed.out, "\x1b[%dD", n)
+	}
+}
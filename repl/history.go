@@ -0,0 +1,74 @@
+package repl
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+)
+
+// HISTORY_ENV 指定历史记录文件，未设置时使用主目录下的 .monkey_history
+const HISTORY_ENV = "MONKEY_HISTORY"
+
+// maxHistory 最多保留的历史记录条数
+const maxHistory = 1000
+
+// history 保存输入过的行，path 不为空时追加写入文件
+type history struct {
+	entries []string
+	path    string
+}
+
+func historyPath() string {
+	if path := os.Getenv(HISTORY_ENV); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".monkey_history")
+}
+
+// loadHistory 读取历史记录文件，文件不存在时返回空的历史记录
+func loadHistory(path string) *history {
+	h := &history{path: path}
+	if path == "" {
+		return h
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return h
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		h.entries = append(h.entries, scanner.Text())
+	}
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[len(h.entries)-maxHistory:]
+	}
+	return h
+}
+
+// add 记录一行输入，忽略空行和与上一条相同的行
+func (h *history) add(line string) {
+	if line == "" || (len(h.entries) > 0 && h.entries[len(h.entries)-1] == line) {
+		return
+	}
+	h.entries = append(h.entries, line)
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[1:]
+	}
+
+	if h.path == "" {
+		return
+	}
+	// 历史记录只是辅助功能，写入失败时忽略
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.WriteString(line + "\n")
+}
@@ -0,0 +1,102 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// CONTINUE_PROMPT 是输入没有结束时后续行的提示符
+const CONTINUE_PROMPT = ".."
+
+// errInterrupt 用户按下 Ctrl-C，放弃正在输入的内容
+var errInterrupt = errors.New("interrupt")
+
+// lineReader 显示提示符并读取一行输入，输入结束时返回 io.EOF
+type lineReader interface {
+	ReadLine(prompt string) (string, error)
+}
+
+// newLineReader 在 in 是终端时支持行编辑、历史记录和 Tab 补全，否则逐行读取
+func newLineReader(in io.Reader, out io.Writer, complete func(string) ([]string, int)) lineReader {
+	if f, ok := in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		ed := newEditor(f, out, loadHistory(historyPath()))
+		ed.complete = complete
+		return &terminal{fd: int(f.Fd()), editor: ed}
+	}
+	return &scanner{in: bufio.NewScanner(in), out: out}
+}
+
+type scanner struct {
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+func (s *scanner) ReadLine(prompt string) (string, error) {
+	fmt.Fprint(s.out, prompt)
+	if !s.in.Scan() {
+		if err := s.in.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return s.in.Text(), nil
+}
+
+// readInput 读取一段完整的输入，括号或字符串没有结束时继续读取下一行
+func readInput(r lineReader) (string, error) {
+	prompt := PROMPT
+	var lines []string
+	for {
+		line, err := r.ReadLine(prompt)
+		if err == io.EOF && len(lines) != 0 {
+			// 交给解析器报告没有结束的输入
+			return strings.Join(lines, "\n"), nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		lines = append(lines, line)
+		src := strings.Join(lines, "\n")
+		if !incomplete(src) {
+			return src, nil
+		}
+		prompt = CONTINUE_PROMPT
+	}
+}
+
+// incomplete 判断 src 是否有没有闭合的括号或字符串。
+// 右括号多于左括号时认为输入已经结束，由解析器报告错误。
+func incomplete(src string) bool {
+	depth := 0
+	for i := 0; i < len(src); i++ {
+		switch ch := src[i]; {
+		case ch == '"':
+			end := strings.IndexByte(src[i+1:], '"')
+			if end < 0 {
+				return true
+			}
+			i += end + 1
+		case ch == '/' && i+1 < len(src) && src[i+1] == '/':
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				return depth > 0
+			}
+			i += end
+		case ch == '(' || ch == '[' || ch == '{':
+			depth++
+		case ch == ')' || ch == ']' || ch == '}':
+			depth--
+			if depth < 0 {
+				return false
+			}
+		}
+	}
+	return depth > 0
+}
@@ -1,42 +1,32 @@
 package repl
 
 import (
-	"bufio"
-	"fmt"
+	"errors"
 	"io"
-	"shanyl2400/go_compiler/evaluator"
-	"shanyl2400/go_compiler/lexer"
-	"shanyl2400/go_compiler/object"
-	"shanyl2400/go_compiler/parser"
+	"strings"
 )
 
 const PROMPT = ">>"
 
 func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
-	env := object.NewEnvironment()
+	s := newSession(out)
+	reader := newLineReader(in, out, s.complete)
 
 	for {
-		fmt.Fprintf(out, PROMPT)
-		scanned := scanner.Scan()
-		if !scanned {
+		input, err := readInput(reader)
+		if errors.Is(err, errInterrupt) {
+			continue
+		}
+		if err != nil {
 			return
 		}
 
-		line := scanner.Text()
-		l := lexer.New(line)
-		p := parser.New(l)
-
-		program := p.ParseProgram()
-		if len(p.Errors()) != 0 {
-			printParseErrors(out, p.Errors())
+		if strings.HasPrefix(strings.TrimSpace(input), ":") {
+			s.command(input)
 			continue
 		}
-
-		evaluated := evaluator.Eval(program, env)
-		if evaluated != nil {
-			io.WriteString(out, evaluated.Inspect())
-			io.WriteString(out, "\n")
+		if program := s.parse(input); program != nil {
+			s.run(program)
 		}
 	}
 }
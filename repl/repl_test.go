@@ -0,0 +1,241 @@
+package repl
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIncomplete(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"let x = 1;", false},
+		{"let add = fn(a, b) {", true},
+		{"let add = fn(a, b) {\n  a + b\n}", false},
+		{"add(1,", true},
+		{"[1, 2", true},
+		{`let s = "abc`, true},
+		{`let s = "a{b"`, false},
+		{"let x = 1; // {", false},
+		{"if (x) { // }", true},
+		{"}", false},
+		{"1 + 2)", false},
+	}
+
+	for _, tt := range tests {
+		if got := incomplete(tt.input); got != tt.expected {
+			t.Errorf("incomplete(%q) = %t, want %t", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestStartMultiLine(t *testing.T) {
+	input := "let add = fn(a, b) {\n  a + b\n};\nadd(1,\n2)\nlet s = \"x\ny\";\nlen(s)\nadd(1,\n"
+
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out)
+
+	expected := ">>.." + ".." + "fn(a, b) {\n(a + b)\n}\n" +
+		">>" + ".." + "3\n" +
+		">>" + ".." + "x\ny\n" +
+		">>" + "3\n" +
+		">>" + ".." + "Woops!"
+	if !strings.HasPrefix(out.String(), expected) {
+		t.Errorf("wrong output.\nwant prefix=%q\ngot=%q", expected, out.String())
+	}
+}
+
+func TestEditor(t *testing.T) {
+	tests := []struct {
+		keys     string
+		history  []string
+		expected []string
+	}{
+		{"abc\r", nil, []string{"abc"}},
+		// 退格和左右方向键
+		{"abd\x7fc\x1b[D\x1b[DX\r", nil, []string{"aXbc"}},
+		// Home、End、Delete
+		{"bc\x1b[Ha\x1b[Fd\x1b[H\x1b[3~\r", nil, []string{"bcd"}},
+		// Ctrl-A、Ctrl-E、Ctrl-K、Ctrl-U
+		{"world\x01hello \x05!\x02\x02\x0b\r", nil, []string{"hello worl"}},
+		{"hello world\x02\x02\x02\x02\x02\x15\r", nil, []string{"world"}},
+		// 上下方向键浏览历史记录，回到最新时恢复正在输入的内容
+		{"\x1b[A\r", []string{"one", "two"}, []string{"two"}},
+		{"\x1b[A\x1b[A\x1b[A\r", []string{"one", "two"}, []string{"one"}},
+		{"dra\x1b[A\x1b[Bft\r", []string{"one"}, []string{"draft"}},
+		{"one\rtwo\r\x10\x10\r", nil, []string{"one", "two", "one"}},
+		// 非 ASCII 字符
+		{"你好\x1b[D!\r", nil, []string{"你!好"}},
+	}
+
+	for _, tt := range tests {
+		h := &history{entries: tt.history}
+		ed := newEditor(strings.NewReader(tt.keys), io.Discard, h)
+
+		var lines []string
+		for {
+			line, err := ed.ReadLine(PROMPT)
+			if err != nil {
+				break
+			}
+			lines = append(lines, line)
+		}
+		if strings.Join(lines, "|") != strings.Join(tt.expected, "|") {
+			t.Errorf("keys %q: got=%q, want=%q", tt.keys, lines, tt.expected)
+		}
+	}
+}
+
+func TestEditorInterrupt(t *testing.T) {
+	ed := newEditor(strings.NewReader("abc\x03\x04"), io.Discard, &history{})
+	if _, err := ed.ReadLine(PROMPT); err != errInterrupt {
+		t.Errorf("expected errInterrupt, got=%v", err)
+	}
+	if _, err := ed.ReadLine(PROMPT); err != io.EOF {
+		t.Errorf("expected io.EOF on Ctrl-D, got=%v", err)
+	}
+}
+
+func TestHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+
+	h := loadHistory(path)
+	ed := newEditor(strings.NewReader("let x = 1;\rx\r\rx\r"), io.Discard, h)
+	for {
+		if _, err := ed.ReadLine(PROMPT); err != nil {
+			break
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "let x = 1;\nx\n" {
+		t.Errorf("wrong history file. got=%q", data)
+	}
+
+	h = loadHistory(path)
+	if strings.Join(h.entries, "|") != "let x = 1;|x" {
+		t.Errorf("wrong history entries. got=%q", h.entries)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib.mk")
+	if err := os.WriteFile(lib, []byte("let double = fn(x) { x * 2 };\ndouble(21)\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{":env\n", ">>add = fn(a, b)\nx = 3\n"},
+		{":type x\n", ">>INTEGER\n"},
+		{":type add\n", ">>FUNCTION\n"},
+		{":type null\n", ">>NULL\n"},
+		{":type y\n", ">>ERROR: identifier not found: y\n    at <main> (<input>:1)\n"},
+		{":ast -a + b * 2\n", ">>Program\n  ExpressionStatement\n    InfixExpression +\n" +
+			"      PrefixExpression -\n        Identifier a\n      InfixExpression *\n" +
+			"        Identifier b\n        IntegerLiteral 2\n"},
+		{":ast {\"k\": [1]}\n", ">>Program\n  ExpressionStatement\n    HashLiteral\n      HashPair\n" +
+			"        StringLiteral \"k\"\n        ArrayLiteral\n          IntegerLiteral 1\n"},
+		{":ast let = 1\n", ">>Woops!"},
+		{":bytecode 1 + 2\n", ">>no instructions: the compiler does not generate bytecode for this code yet\n"},
+		{":load " + lib + "\ndouble(x)\n", ">>42\n>>6\n"},
+		{":load " + filepath.Join(dir, "missing.mk") + "\n", ">>open "},
+		{":reset\nx\n", ">>environment cleared\n>>ERROR: identifier not found: x\n"},
+		{":time add(x, 1)\n", ">>4\ntook "},
+		{":type\n", ">>usage: :type EXPR\n"},
+		{":nope\n", ">>unknown command: :nope (type :help for a list)\n"},
+		{":help\n", ">>  :help            show this list\n"},
+	}
+
+	for _, tt := range tests {
+		setup := "let add = fn(a, b) { a + b };\nlet x = add(1, 2);\n"
+
+		var out bytes.Buffer
+		Start(strings.NewReader(setup+tt.input), &out)
+
+		got := strings.TrimPrefix(out.String(), ">>fn(a, b) {\n(a + b)\n}\n>>3\n")
+		if !strings.HasPrefix(got, tt.expected) {
+			t.Errorf("input %q: wrong output.\nwant prefix=%q\ngot=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestComplete(t *testing.T) {
+	s := newSession(io.Discard)
+	program := s.parse(`let counter = 1; let count = fn(x) { x }; let h = {"name": 1, "nick": 2, 3: 4};`)
+	s.run(program)
+
+	tests := []struct {
+		before     string
+		candidates []string
+		replaced   int
+	}{
+		{"cou", []string{"count", "counter"}, 3},
+		{"puts(coun", []string{"count", "counter"}, 4},
+		{"le", []string{"len", "let"}, 2},
+		{"wh", []string{"while"}, 2},
+		{"h[", []string{`"name"]`, `"nick"]`}, 0},
+		{`h["na`, []string{`"name"]`}, 3},
+		{`h?["n`, []string{`"name"]`, `"nick"]`}, 2},
+		{"counter[", nil, 0},
+		{"missing[", nil, 0},
+		{`puts("cou`, nil, 0},
+		{"h.na", nil, 0},
+		{"1 + ", nil, 0},
+		{"zzz", nil, 3},
+	}
+
+	for _, tt := range tests {
+		candidates, n := s.complete(tt.before)
+		if strings.Join(candidates, "|") != strings.Join(tt.candidates, "|") || n != tt.replaced {
+			t.Errorf("complete(%q) = %q, %d; want %q, %d", tt.before, candidates, n, tt.candidates, tt.replaced)
+		}
+	}
+}
+
+func TestEditorTab(t *testing.T) {
+	s := newSession(io.Discard)
+	s.run(s.parse(`let h = {"name": 1};`))
+
+	tests := []struct {
+		keys     string
+		expected string
+		listed   string
+	}{
+		{"put\t(1)\r", "puts(1)", ""},
+		{"()\x1b[Dpus\t\r", "(push)", ""},
+		{"h[\t\r", `h["name"]`, ""},
+		{"fi\t\r", "fi", "finally  first"},
+		{"le\t\r", "le", "len  let"},
+		{"mer\t\r", "merge", ""},
+		{"\t\r", "", ""},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		ed := newEditor(strings.NewReader(tt.keys), &out, &history{})
+		ed.complete = s.complete
+
+		line, err := ed.ReadLine(PROMPT)
+		if err != nil {
+			t.Fatalf("keys %q: %v", tt.keys, err)
+		}
+		if line != tt.expected {
+			t.Errorf("keys %q: got=%q, want=%q", tt.keys, line, tt.expected)
+		}
+		if tt.listed != "" && !strings.Contains(out.String(), "\r\n"+tt.listed+"\r\n") {
+			t.Errorf("keys %q: candidates %q not listed in %q", tt.keys, tt.listed, out.String())
+		}
+	}
+}
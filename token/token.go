@@ -1,5 +1,7 @@
 package token
 
+import "sort"
+
 const (
 	ILLEGAL = "ILLEGAL"
 	EOF     = "EOF"
@@ -20,6 +22,14 @@ const (
 	COMMA     = ","
 	SEMICOLON = ";"
 	COLON     = ":"
+	DOT       = "."
+
+	NULLISH      = "??"
+	OPT_DOT      = "?."
+	OPT_LBRACKET = "?["
+
+	// COMMENT 只出现在 Lexer.Comments 中，NextToken 不会返回
+	COMMENT = "COMMENT"
 
 	LPAREN   = "("
 	RPAREN   = ")"
@@ -41,8 +51,17 @@ const (
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	NULL     = "NULL"
 
 	WHILE = "WHILE"
+
+	TRY     = "TRY"
+	CATCH   = "CATCH"
+	FINALLY = "FINALLY"
+	THROW   = "THROW"
+
+	IMPORT = "IMPORT"
+	EXPORT = "EXPORT"
 )
 
 var keywords = map[string]TokenType{
@@ -53,8 +72,17 @@ var keywords = map[string]TokenType{
 	"if":     IF,
 	"else":   ELSE,
 	"return": RETURN,
+	"null":   NULL,
 
 	"while": WHILE,
+
+	"try":     TRY,
+	"catch":   CATCH,
+	"finally": FINALLY,
+	"throw":   THROW,
+
+	"import": IMPORT,
+	"export": EXPORT,
 }
 
 type TokenType string
@@ -62,6 +90,10 @@ type TokenType string
 type Token struct {
 	Type    TokenType
 	Literal string
+
+	// Line 和 Column 是 token 在源码中的位置，从 1 开始
+	Line   int
+	Column int
 }
 
 // 区分关键字和标识符
@@ -71,3 +103,13 @@ func LookupIdent(ident string) TokenType {
 	}
 	return IDENT
 }
+
+// Keywords 返回全部关键字，按字母排序
+func Keywords() []string {
+	names := make([]string, 0, len(keywords))
+	for name := range keywords {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}